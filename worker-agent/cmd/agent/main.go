@@ -1,14 +1,20 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
+	"golang.org/x/term"
+
 	"github.com/finetunelab/worker-agent/internal/agent"
+	"github.com/finetunelab/worker-agent/internal/logging"
 )
 
 const version = "0.1.0"
@@ -39,7 +45,6 @@ func main() {
 			log.Fatalf("Failed to initialize config: %v", err)
 		}
 		fmt.Printf("Config file initialized at: %s\n", *configPath)
-		fmt.Println("Please edit the config file and set your API key.")
 		os.Exit(0)
 	}
 
@@ -49,21 +54,28 @@ func main() {
 		log.Fatalf("Failed to load config: %v\n\nRun with -init to create a default config file.", err)
 	}
 
-	// Setup logging
+	// Setup structured logging
+	var logOutput io.Writer
 	if cfg.LogFile != "" {
 		logFile, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
 			log.Fatalf("Failed to open log file: %v", err)
 		}
 		defer logFile.Close()
-		log.SetOutput(logFile)
+		logOutput = logFile
 	}
 
-	log.Printf("FineTuneLab Worker Agent v%s", version)
-	log.Printf("Config file: %s", *configPath)
+	logger, ringBuffer := logging.New(logging.Options{
+		Name:   "worker-agent",
+		Level:  cfg.LogLevel,
+		JSON:   cfg.LogJSON,
+		Output: logOutput,
+	})
+
+	logger.Info("starting worker agent", "version", version, "config_file", *configPath)
 
 	// Create agent
-	ag, err := agent.New(cfg)
+	ag, err := agent.New(cfg, *configPath, logger, ringBuffer)
 	if err != nil {
 		log.Fatalf("Failed to create agent: %v", err)
 	}
@@ -99,6 +111,18 @@ func initializeConfig(path string) error {
 	cfg := agent.DefaultConfig()
 	cfg.LogFile = agent.GetLogPath()
 
+	apiKey, err := promptAPIKey()
+	if err != nil {
+		return fmt.Errorf("failed to read API key: %w", err)
+	}
+
+	if ref, err := agent.StoreAPIKey(apiKey); err != nil {
+		fmt.Printf("Warning: failed to store API key in OS keyring (%v); saving it in plaintext in the config file instead.\n", err)
+		cfg.APIKey = apiKey
+	} else {
+		cfg.APIKey = ref
+	}
+
 	// Save to file
 	if err := cfg.SaveConfig(path); err != nil {
 		return err
@@ -106,3 +130,25 @@ func initializeConfig(path string) error {
 
 	return nil
 }
+
+// promptAPIKey reads the worker's API key from stdin without echoing it to
+// the terminal, falling back to a visible read when stdin isn't a terminal
+// (e.g. piped input in scripted setups).
+func promptAPIKey() (string, error) {
+	fmt.Print("Enter your FineTuneLab worker API key: ")
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		keyBytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(keyBytes)), nil
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
@@ -1,223 +1,471 @@
-package agent
-
-import (
-	"context"
-	"fmt"
-	"log"
-	"os"
-	"runtime"
-	"sync"
-	"time"
-
-	"github.com/finetunelab/worker-agent/internal/client"
-	"github.com/finetunelab/worker-agent/internal/collector"
-	"github.com/finetunelab/worker-agent/internal/executor"
-	"github.com/finetunelab/worker-agent/pkg/api"
-)
-
-// Agent is the main worker agent
-type Agent struct {
-	config            *Config
-	httpClient        *client.HTTPClient
-	workerID          string
-	executor          *executor.Executor
-	metricsCollector  *collector.MetricsCollector
-	ctx               context.Context
-	cancel            context.CancelFunc
-	wg                sync.WaitGroup
-}
-
-// New creates a new worker agent
-func New(config *Config) (*Agent, error) {
-	// Validate config
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
-	}
-
-	// Create HTTP client
-	httpClient := client.NewHTTPClient(config.BaseURL, config.APIKey)
-
-	// Create executor
-	exec := executor.New()
-
-	// Create metrics collector
-	metricsCollector := collector.NewMetricsCollector()
-
-	ctx, cancel := context.WithCancel(context.Background())
-
-	return &Agent{
-		config:           config,
-		httpClient:       httpClient,
-		workerID:         config.WorkerID,
-		executor:         exec,
-		metricsCollector: metricsCollector,
-		ctx:              ctx,
-		cancel:           cancel,
-	}, nil
-}
-
-// Start starts the worker agent
-func (a *Agent) Start() error {
-	log.Println("[Agent] Starting worker agent...")
-
-	// Register with SaaS if not already registered
-	if a.workerID == "" {
-		if err := a.register(); err != nil {
-			return fmt.Errorf("registration failed: %w", err)
-		}
-	}
-
-	log.Printf("[Agent] Worker ID: %s", a.workerID)
-
-	// Start heartbeat loop
-	a.wg.Add(1)
-	go a.heartbeatLoop()
-
-	log.Println("[Agent] Worker agent started successfully")
-
-	return nil
-}
-
-// Stop stops the worker agent gracefully
-func (a *Agent) Stop() error {
-	log.Println("[Agent] Stopping worker agent...")
-
-	// Cancel context
-	a.cancel()
-
-	// Wait for goroutines to finish
-	done := make(chan struct{})
-	go func() {
-		a.wg.Wait()
-		close(done)
-	}()
-
-	// Wait with timeout
-	select {
-	case <-done:
-		log.Println("[Agent] Worker agent stopped successfully")
-		return nil
-	case <-time.After(10 * time.Second):
-		log.Println("[Agent] Worker agent stop timeout - forcing shutdown")
-		return fmt.Errorf("shutdown timeout")
-	}
-}
-
-// register registers the worker with the SaaS
-func (a *Agent) register() error {
-	log.Println("[Agent] Registering worker with SaaS...")
-
-	hostname, _ := os.Hostname()
-	if hostname == "" {
-		hostname = a.config.Hostname
-	}
-
-	req := &api.RegisterRequest{
-		APIKey:       a.config.APIKey,
-		Hostname:     hostname,
-		Platform:     runtime.GOOS,
-		Version:      a.config.Version,
-		Capabilities: a.config.Capabilities,
-		Metadata: map[string]string{
-			"go_version": runtime.Version(),
-			"arch":       runtime.GOARCH,
-		},
-	}
-
-	resp, err := a.httpClient.Register(req)
-	if err != nil {
-		return fmt.Errorf("registration request failed: %w", err)
-	}
-
-	// Save worker ID and other info
-	a.workerID = resp.WorkerID
-	a.config.WorkerID = resp.WorkerID
-	a.config.HeartbeatIntervalSeconds = resp.HeartbeatIntervalSeconds
-	a.config.MaxConcurrency = resp.MaxConcurrency
-
-	// Save updated config
-	configPath := GetConfigPath()
-	if err := a.config.SaveConfig(configPath); err != nil {
-		log.Printf("[Agent] Warning: Failed to save config: %v", err)
-	}
-
-	log.Printf("[Agent] Registered successfully. Worker ID: %s", a.workerID)
-
-	return nil
-}
-
-// heartbeatLoop sends periodic heartbeats
-func (a *Agent) heartbeatLoop() {
-	defer a.wg.Done()
-
-	interval := time.Duration(a.config.HeartbeatIntervalSeconds) * time.Second
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	log.Printf("[Agent] Starting heartbeat loop (interval: %v)", interval)
-
-	for {
-		select {
-		case <-a.ctx.Done():
-			log.Println("[Agent] Heartbeat loop stopped")
-			return
-		case <-ticker.C:
-			if err := a.sendHeartbeat(); err != nil {
-				log.Printf("[Agent] Heartbeat error: %v", err)
-			}
-		}
-	}
-}
-
-// sendHeartbeat sends a heartbeat to the SaaS
-func (a *Agent) sendHeartbeat() error {
-	// Collect metrics
-	metrics, err := a.metricsCollector.Collect()
-	if err != nil {
-		log.Printf("[Agent] Warning: Failed to collect metrics: %v", err)
-		metrics = &api.MetricsSnapshot{}
-	}
-
-	req := &api.HeartbeatRequest{
-		Status:  "online",
-		Metrics: metrics,
-	}
-
-	resp, err := a.httpClient.Heartbeat(a.workerID, req)
-	if err != nil {
-		return fmt.Errorf("heartbeat request failed: %w", err)
-	}
-
-	// Process pending commands
-	if len(resp.PendingCommands) > 0 {
-		log.Printf("[Agent] Received %d pending command(s)", len(resp.PendingCommands))
-		for _, cmd := range resp.PendingCommands {
-			log.Printf("[Agent] Executing command: %s (ID: %s)", cmd.CommandType, cmd.ID)
-			// Execute command asynchronously
-			go a.executeCommand(cmd)
-		}
-	}
-
-	return nil
-}
-
-// executeCommand executes a command and reports the result
-func (a *Agent) executeCommand(cmd api.Command) {
-	// Execute command with timeout
-	result := a.executor.Execute(a.ctx, cmd)
-
-	// Send result back to SaaS
-	if err := a.httpClient.SendCommandResult(cmd.ID, &result); err != nil {
-		log.Printf("[Agent] Failed to send command result: %v", err)
-		// Retry once after 5 seconds
-		time.Sleep(5 * time.Second)
-		if err := a.httpClient.SendCommandResult(cmd.ID, &result); err != nil {
-			log.Printf("[Agent] Failed to send command result (retry): %v", err)
-		}
-	}
-}
-
-// Wait blocks until the agent is stopped
-func (a *Agent) Wait() {
-	a.wg.Wait()
-}
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/finetunelab/worker-agent/internal/client"
+	"github.com/finetunelab/worker-agent/internal/collector"
+	"github.com/finetunelab/worker-agent/internal/diagnostics"
+	"github.com/finetunelab/worker-agent/internal/executor"
+	"github.com/finetunelab/worker-agent/internal/logging"
+	"github.com/finetunelab/worker-agent/internal/spool"
+	"github.com/finetunelab/worker-agent/internal/updater"
+	"github.com/finetunelab/worker-agent/internal/ws"
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+// pollingHeartbeatInterval is the interval used for HTTP heartbeats while
+// the WS command channel is disconnected, so pending commands still reach
+// the worker in a reasonable time.
+const pollingHeartbeatInterval = 5 * time.Second
+
+// logShipInterval is how often the log ring buffer's tail is pushed to the
+// SaaS for remote triage, independent of the heartbeat cadence.
+const logShipInterval = 1 * time.Minute
+
+// Agent is the main worker agent
+type Agent struct {
+	config           *Config
+	configPath       string
+	logger           hclog.Logger
+	logRingBuffer    *logging.RingBuffer
+	httpClient       *client.HTTPClient
+	workerID         string
+	executor         *executor.Executor
+	metricsCollector *collector.MetricsCollector
+	updater          *updater.Updater
+	spooler          *spool.Spooler
+	configManager    *ConfigManager
+	wsClient         *ws.Client
+	wsConnected      atomic.Bool
+	ctx              context.Context
+	cancel           context.CancelFunc
+	wg               sync.WaitGroup
+}
+
+// New creates a new worker agent. logger is the root structured logger
+// (component sub-loggers are derived from it via Named); ringBuffer backs
+// its diagnostics log tail and may be nil if the caller doesn't need one.
+// configPath is the file config was actually loaded from (e.g. via -config);
+// it anchors the config watcher and the config-adjacent state files
+// (outbox, spool, diagnostics, restart state) to that same directory
+// instead of assuming the platform default.
+func New(config *Config, configPath string, logger hclog.Logger, ringBuffer *logging.RingBuffer) (*Agent, error) {
+	// Validate config
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+
+	// Create HTTP client with a durable outbox alongside the config file
+	outboxPath := filepath.Join(filepath.Dir(configPath), "outbox.jsonl")
+	httpClient, err := client.NewHTTPClient(config.BaseURL, config.ResolvedAPIKey(), outboxPath, logger.Named("client"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	// Create the self-updater
+	updateSigningKey, err := config.UpdateSigningKey()
+	if err != nil {
+		return nil, fmt.Errorf("invalid update signing key: %w", err)
+	}
+	upd, err := updater.New(updater.Config{
+		BaseURL:        config.BaseURL,
+		CurrentVersion: config.Version,
+		PublicKey:      updateSigningKey,
+	}, logger.Named("updater"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create updater: %w", err)
+	}
+
+	// Create the command spool
+	spoolDir := filepath.Join(filepath.Dir(configPath), "spool")
+	spooler, err := spool.Open(spool.Config{
+		Dir:           spoolDir,
+		MaxAge:        time.Duration(config.SpoolMaxAgeHours) * time.Hour,
+		MaxSize:       config.SpoolMaxSizeMB * 1024 * 1024,
+		MaxFiles:      config.SpoolMaxFiles,
+		SweepInterval: time.Duration(config.SpoolSweepIntervalMinutes) * time.Minute,
+	}, logger.Named("spool"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open command spool: %w", err)
+	}
+
+	// Create the diagnostics bundle collector
+	diagDir := filepath.Join(filepath.Dir(configPath), "diagnostics")
+	diag, err := diagnostics.New(diagnostics.Config{
+		Dir:       diagDir,
+		MaxSizeMB: config.DiagMaxBundleSizeMB,
+	}, logger.Named("diagnostics"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create diagnostics collector: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Create the config manager backing update_config's hot-reload pipeline
+	configManager := NewConfigManager(config, configPath, logger.Named("config"))
+
+	// Create executor
+	signingKey, err := config.CommandSigningKey()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid command signing key: %w", err)
+	}
+	restartStatePath := filepath.Join(filepath.Dir(configPath), "restart-state.json")
+	drainTimeout := time.Duration(config.RestartDrainTimeoutSeconds) * time.Second
+	exec := executor.New(ctx, signingKey, ringBuffer, upd, spooler, diag, configManager, restartStatePath, drainTimeout, logger.Named("executor"))
+
+	// Subscribe the subsystems a hot config reload can update in place
+	configManager.Register(loggerReloadable{logger: logger})
+	configManager.Register(spoolReloadable{spooler: spooler})
+	configManager.Register(tradingReloadable{ctx: ctx, ts: exec.Trading()})
+	configManager.Register(signingKeyReloadable{executor: exec})
+	configManager.Register(updaterReloadable{updater: upd})
+
+	// Create metrics collector
+	metricsCollector := collector.NewMetricsCollector(config.ProcessAllowlist, logger.Named("collector"))
+
+	return &Agent{
+		config:           config,
+		configPath:       configPath,
+		logger:           logger,
+		logRingBuffer:    ringBuffer,
+		httpClient:       httpClient,
+		workerID:         config.WorkerID,
+		executor:         exec,
+		metricsCollector: metricsCollector,
+		updater:          upd,
+		spooler:          spooler,
+		configManager:    configManager,
+		ctx:              ctx,
+		cancel:           cancel,
+	}, nil
+}
+
+// Start starts the worker agent
+func (a *Agent) Start() error {
+	a.logger.Info("starting worker agent")
+
+	// Register with SaaS if not already registered
+	if a.workerID == "" {
+		if err := a.register(); err != nil {
+			return fmt.Errorf("registration failed: %w", err)
+		}
+	}
+	a.logger = a.logger.With("worker_id", a.workerID)
+
+	a.logger.Info("worker registered")
+
+	// Start the WS command channel if the SaaS advertised one
+	if a.config.WebSocketURL != "" {
+		a.wsClient = ws.NewClient(a.config.WebSocketURL, a.config.ResolvedAPIKey(), a.config.Capabilities, a.executeCommandViaWS, a.setWSConnected, a.deliverWSResultFallback)
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.wsClient.Run(a.ctx)
+		}()
+	}
+
+	// Start heartbeat loop
+	a.wg.Add(1)
+	go a.heartbeatLoop()
+
+	// If this process was just installed by a self-update, watch for
+	// confirmation (a successful heartbeat) and roll back if it never
+	// comes within the rollback window.
+	a.wg.Add(1)
+	go a.watchForUpdateRollback()
+
+	// Start draining the durable outbox of queued command results/metrics
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.httpClient.RunOutbox(a.ctx)
+	}()
+
+	// Start the background command spool sweeper
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.spooler.Run(a.ctx)
+	}()
+
+	// Watch the config file for on-disk edits and hot-reload them
+	a.wg.Add(1)
+	go a.watchConfigFile()
+
+	// Periodically push the log ring buffer's tail to the SaaS for remote
+	// triage, so operators don't need host SSH access to see recent logs
+	if a.logRingBuffer != nil {
+		a.wg.Add(1)
+		go a.shipLogsLoop()
+	}
+
+	// Start the optional local Prometheus exposition listener
+	if a.config.MetricsListenAddr != "" {
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			if err := collector.StartPrometheusListener(a.ctx, a.config.MetricsListenAddr, a.metricsCollector); err != nil {
+				a.logger.Error("prometheus listener error", "error", err)
+			}
+		}()
+	}
+
+	a.logger.Info("worker agent started successfully")
+
+	return nil
+}
+
+// Stop stops the worker agent gracefully
+func (a *Agent) Stop() error {
+	a.logger.Info("stopping worker agent")
+
+	// Cancel context
+	a.cancel()
+
+	// Wait for goroutines to finish
+	done := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(done)
+	}()
+
+	// Wait with timeout
+	select {
+	case <-done:
+		a.logger.Info("worker agent stopped successfully")
+		return nil
+	case <-time.After(10 * time.Second):
+		a.logger.Warn("worker agent stop timeout, forcing shutdown")
+		return fmt.Errorf("shutdown timeout")
+	}
+}
+
+// register registers the worker with the SaaS
+func (a *Agent) register() error {
+	a.logger.Info("registering worker with SaaS")
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = a.config.Hostname
+	}
+
+	req := &api.RegisterRequest{
+		APIKey:       a.config.ResolvedAPIKey(),
+		Hostname:     hostname,
+		Platform:     runtime.GOOS,
+		Version:      a.config.Version,
+		Capabilities: a.config.Capabilities,
+		Metadata: map[string]string{
+			"go_version": runtime.Version(),
+			"arch":       runtime.GOARCH,
+		},
+	}
+
+	resp, err := a.httpClient.Register(req)
+	if err != nil {
+		return fmt.Errorf("registration request failed: %w", err)
+	}
+
+	// Save worker ID and other info
+	a.workerID = resp.WorkerID
+	a.config.WorkerID = resp.WorkerID
+	a.config.HeartbeatIntervalSeconds = resp.HeartbeatIntervalSeconds
+	a.config.MaxConcurrency = resp.MaxConcurrency
+	a.config.WebSocketURL = resp.WebSocketURL
+
+	// Save updated config
+	if err := a.config.SaveConfig(a.configPath); err != nil {
+		a.logger.Warn("failed to save config", "error", err)
+	}
+
+	a.logger.Info("registered successfully", "worker_id", a.workerID)
+
+	return nil
+}
+
+// heartbeatLoop sends periodic heartbeats. When the WS command channel is
+// connected, the HTTP heartbeat is just a keepalive and runs at the
+// SaaS-configured interval. When the channel is down, commands can only
+// reach us via HeartbeatResponse.PendingCommands, so we fall back to the
+// much shorter pollingHeartbeatInterval until it reconnects.
+func (a *Agent) heartbeatLoop() {
+	defer a.wg.Done()
+
+	a.logger.Info("starting heartbeat loop", "keepalive_interval", a.heartbeatInterval())
+
+	timer := time.NewTimer(a.heartbeatInterval())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			a.logger.Info("heartbeat loop stopped")
+			return
+		case <-timer.C:
+			if err := a.sendHeartbeat(); err != nil {
+				a.logger.Error("heartbeat error", "error", err)
+			}
+			timer.Reset(a.heartbeatInterval())
+		}
+	}
+}
+
+// shipLogsLoop periodically pushes the log ring buffer's current tail to
+// the SaaS until ctx is canceled. Failures are logged and retried on the
+// next tick; log shipping is best-effort and doesn't go through the
+// durable outbox like command results and metrics do.
+func (a *Agent) shipLogsLoop() {
+	defer a.wg.Done()
+
+	ticker := time.NewTicker(logShipInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-ticker.C:
+			records := a.logRingBuffer.Snapshot()
+			if len(records) == 0 {
+				continue
+			}
+			if err := a.httpClient.SendLogs(a.workerID, records); err != nil {
+				a.logger.Warn("failed to ship logs", "error", err)
+			}
+		}
+	}
+}
+
+// heartbeatInterval returns the current HTTP heartbeat interval, shortened
+// while the WS command channel is disconnected so commands still arrive
+// promptly via polling.
+func (a *Agent) heartbeatInterval() time.Duration {
+	if a.wsClient != nil && !a.wsConnected.Load() {
+		return pollingHeartbeatInterval
+	}
+	return time.Duration(a.config.HeartbeatIntervalSeconds) * time.Second
+}
+
+// setWSConnected updates the cached WS connection state used to decide the
+// HTTP heartbeat cadence.
+func (a *Agent) setWSConnected(connected bool) {
+	a.wsConnected.Store(connected)
+	if connected {
+		a.logger.Info("WS command channel connected, resuming keepalive-only heartbeats")
+	} else {
+		a.logger.Warn("WS command channel disconnected, falling back to heartbeat polling")
+	}
+}
+
+// watchForUpdateRollback blocks until the pending-update marker (if any) is
+// confirmed or the rollback window elapses. On rollback it exits the
+// process so the supervisor restarts it into the restored binary.
+func (a *Agent) watchForUpdateRollback() {
+	defer a.wg.Done()
+
+	if err := a.updater.WatchForConfirmation(a.ctx); err != nil {
+		a.logger.Error("self-update rolled back", "error", err)
+		os.Exit(1)
+	}
+}
+
+// sendHeartbeat sends a heartbeat to the SaaS
+func (a *Agent) sendHeartbeat() error {
+	// Collect metrics
+	trading := a.executor.Trading()
+	metrics, err := a.metricsCollector.CollectWithTrading(trading.GetStatus(), len(trading.RecentOrders()))
+	if err != nil {
+		a.logger.Warn("failed to collect metrics", "error", err)
+		metrics = &api.MetricsSnapshot{}
+	}
+
+	// Deliver metrics through the durable outbox too, so a sample taken
+	// during a SaaS outage is retried instead of only ever riding along on
+	// this one heartbeat.
+	if err := a.httpClient.SendMetrics(&api.MetricsBatch{
+		WorkerID: a.workerID,
+		Metrics:  []api.MetricsSnapshot{*metrics},
+	}); err != nil {
+		a.logger.Warn("failed to queue metrics", "error", err)
+	}
+
+	req := &api.HeartbeatRequest{
+		Status:  "online",
+		Metrics: metrics,
+	}
+
+	resp, err := a.httpClient.Heartbeat(a.workerID, req)
+	if err != nil {
+		return fmt.Errorf("heartbeat request failed: %w", err)
+	}
+
+	// A successful heartbeat proves this binary is healthy, so clear any
+	// pending-update marker a self-update left behind.
+	if err := a.updater.ConfirmUpdate(); err != nil {
+		a.logger.Warn("failed to confirm update", "error", err)
+	}
+
+	// Process pending commands
+	if len(resp.PendingCommands) > 0 {
+		a.logger.Info("received pending commands", "count", len(resp.PendingCommands))
+		for _, cmd := range resp.PendingCommands {
+			a.logger.Info("executing command", "command_type", cmd.CommandType, "command_id", cmd.ID)
+			// Execute command asynchronously
+			go a.executeCommand(cmd)
+		}
+	}
+
+	return nil
+}
+
+// executeCommand executes a command received via HTTP heartbeat polling
+// and reports the result back over HTTP. SendCommandResult durably queues
+// the result in the outbox on failure, so no further local retry is
+// needed here.
+func (a *Agent) executeCommand(cmd api.Command) {
+	// Execute command with timeout
+	result := a.executor.Execute(a.ctx, cmd)
+
+	if err := a.httpClient.SendCommandResult(cmd.ID, &result); err != nil {
+		a.logger.Error("failed to queue command result", "command_id", cmd.ID, "error", err)
+	}
+}
+
+// executeCommandViaWS executes a command received over the WS command
+// channel. The result is returned so the ws.Client can push it back as a
+// command_result frame instead of going through the HTTP result endpoint.
+func (a *Agent) executeCommandViaWS(ctx context.Context, cmd api.Command) api.CommandResult {
+	a.logger.Info("executing command via WS", "command_type", cmd.CommandType, "command_id", cmd.ID)
+	return a.executor.Execute(ctx, cmd)
+}
+
+// deliverWSResultFallback is the ws.ResultFallback used when a
+// command_result frame can't be written back over the WS channel (e.g.
+// the connection dropped mid-write). It routes the result through the
+// same durable outbox as the HTTP polling path, so a disconnect at
+// delivery time doesn't silently drop the outcome.
+func (a *Agent) deliverWSResultFallback(cmd api.Command, result api.CommandResult) {
+	if err := a.httpClient.SendCommandResult(cmd.ID, &result); err != nil {
+		a.logger.Error("failed to queue WS command result", "command_id", cmd.ID, "error", err)
+	}
+}
+
+// Wait blocks until the agent is stopped
+func (a *Agent) Wait() {
+	a.wg.Wait()
+}
@@ -1,24 +1,41 @@
 package agent
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/finetunelab/worker-agent/internal/secrets"
+)
+
+// keyringService and keyringAPIKeyAccount locate the worker's API key in
+// the OS keyring when api_key is a "keyring:" reference.
+const (
+	keyringService       = "finetunelab"
+	keyringAPIKeyAccount = "worker-agent"
 )
 
 // Config represents the worker agent configuration
 type Config struct {
-	// API Configuration
+	// API Configuration. APIKey may be a literal key (legacy/dev) or a
+	// "keyring:service/account" reference resolved via ResolvedAPIKey.
 	APIKey  string `yaml:"api_key"`
 	BaseURL string `yaml:"base_url"`
 
+	// resolvedAPIKey caches the result of resolving APIKey through a
+	// secrets.Provider; it is never persisted.
+	resolvedAPIKey string `yaml:"-"`
+
 	// Worker Information
-	WorkerID string `yaml:"worker_id,omitempty"` // Set after registration
-	Hostname string `yaml:"hostname"`
-	Version  string `yaml:"version"`
+	WorkerID     string `yaml:"worker_id,omitempty"`      // Set after registration
+	WebSocketURL string `yaml:"websocket_url,omitempty"` // Set after registration
+	Hostname     string `yaml:"hostname"`
+	Version      string `yaml:"version"`
 
 	// Behavior
 	HeartbeatIntervalSeconds int      `yaml:"heartbeat_interval_seconds"`
@@ -28,10 +45,49 @@ type Config struct {
 	// Logging
 	LogLevel string `yaml:"log_level"` // debug, info, warn, error
 	LogFile  string `yaml:"log_file"`
+	LogJSON  bool   `yaml:"log_json"` // JSON output, suited to log aggregation in production
 
 	// Trading (application-specific)
 	TradingEnabled bool   `yaml:"trading_enabled"`
 	TradingConfig  string `yaml:"trading_config,omitempty"`
+
+	// Metrics
+	ProcessAllowlist  []string `yaml:"process_allowlist,omitempty"`  // process names to report per-process metrics for
+	MetricsListenAddr string   `yaml:"metrics_listen_addr,omitempty"` // e.g. "127.0.0.1:9273"; empty disables the /metrics listener
+
+	// Security
+	CommandSigningPublicKey string `yaml:"command_signing_public_key,omitempty"` // base64-encoded Ed25519 public key; empty disables command signature verification
+
+	// Self-update
+	UpdatePublicKey string `yaml:"update_public_key,omitempty"` // base64-encoded Ed25519 public key; empty disables self-update (refuses to install unverified binaries)
+
+	// Command spool
+	SpoolMaxAgeHours          int   `yaml:"spool_max_age_hours"`          // 0 disables the age limit
+	SpoolMaxSizeMB            int64 `yaml:"spool_max_size_mb"`            // 0 disables the size limit
+	SpoolMaxFiles             int   `yaml:"spool_max_files"`              // 0 disables the file count limit
+	SpoolSweepIntervalMinutes int   `yaml:"spool_sweep_interval_minutes"` // 0 disables the background sweeper
+
+	// Graceful restart
+	RestartDrainTimeoutSeconds int `yaml:"restart_drain_timeout_seconds"` // how long restart_agent waits for in-flight commands to finish before restarting anyway
+
+	// Diagnostics bundles
+	DiagMaxBundleSizeMB int64 `yaml:"diag_max_bundle_size_mb"` // size cap for a collect_diagnostics bundle
+}
+
+// StoreAPIKey stores value in the OS keyring and returns the "keyring:"
+// reference to persist in config.APIKey in place of the literal secret.
+func StoreAPIKey(value string) (string, error) {
+	return secrets.Store(keyringService, keyringAPIKeyAccount, value)
+}
+
+// ResolvedAPIKey returns the worker's API key, resolving a "keyring:"
+// reference if one was configured. LoadConfig populates this eagerly, so
+// callers should normally use it instead of reading APIKey directly.
+func (c *Config) ResolvedAPIKey() string {
+	if c.resolvedAPIKey != "" {
+		return c.resolvedAPIKey
+	}
+	return c.APIKey
 }
 
 // DefaultConfig returns a config with sensible defaults
@@ -42,15 +98,21 @@ func DefaultConfig() *Config {
 	}
 
 	return &Config{
-		BaseURL:                  "https://app.finetunelab.ai",
-		Hostname:                 hostname,
-		Version:                  "0.1.0",
-		HeartbeatIntervalSeconds: 30,
-		MaxConcurrency:           1,
-		Capabilities:             []string{"metrics", "trading"},
-		LogLevel:                 "info",
-		LogFile:                  "",
-		TradingEnabled:           false,
+		BaseURL:                    "https://app.finetunelab.ai",
+		Hostname:                   hostname,
+		Version:                    "0.1.0",
+		HeartbeatIntervalSeconds:   30,
+		MaxConcurrency:             1,
+		Capabilities:               []string{"metrics", "trading"},
+		LogLevel:                   "info",
+		LogFile:                    "",
+		TradingEnabled:             false,
+		SpoolMaxAgeHours:           24 * 7,
+		SpoolMaxSizeMB:             100,
+		SpoolMaxFiles:              10000,
+		SpoolSweepIntervalMinutes:  60,
+		RestartDrainTimeoutSeconds: 30,
+		DiagMaxBundleSizeMB:        50,
 	}
 }
 
@@ -65,11 +127,25 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Transparently decrypt age-encrypted config files, for air-gapped
+	// hosts that can't rely on the OS keyring alone.
+	data, err = secrets.DecryptConfig(data, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt config file: %w", err)
+	}
+
 	// Parse YAML
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// Resolve a "keyring:" api_key reference into the real secret
+	resolvedAPIKey, err := secrets.KeyringEnvProvider{}.Resolve(cfg.APIKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve api_key: %w", err)
+	}
+	cfg.resolvedAPIKey = resolvedAPIKey
+
 	// Validate
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
@@ -92,6 +168,12 @@ func (c *Config) SaveConfig(path string) error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	// Encrypt at rest if this host has an age-recipients file configured
+	data, err = secrets.EncryptConfigIfConfigured(data, path)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt config: %w", err)
+	}
+
 	// Write file
 	if err := os.WriteFile(path, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
@@ -102,7 +184,7 @@ func (c *Config) SaveConfig(path string) error {
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
-	if c.APIKey == "" {
+	if c.ResolvedAPIKey() == "" {
 		return fmt.Errorf("api_key is required")
 	}
 
@@ -121,6 +203,45 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// CommandSigningKey decodes CommandSigningPublicKey into an Ed25519 public
+// key. An empty CommandSigningPublicKey returns a nil key, which disables
+// command signature verification.
+func (c *Config) CommandSigningKey() (ed25519.PublicKey, error) {
+	if c.CommandSigningPublicKey == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(c.CommandSigningPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode command_signing_public_key: %w", err)
+	}
+
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("command_signing_public_key must be %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+
+	return ed25519.PublicKey(key), nil
+}
+
+// UpdateSigningKey decodes UpdatePublicKey into an Ed25519 public key. An
+// empty UpdatePublicKey returns a nil key, which disables self-update.
+func (c *Config) UpdateSigningKey() (ed25519.PublicKey, error) {
+	if c.UpdatePublicKey == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(c.UpdatePublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode update_public_key: %w", err)
+	}
+
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("update_public_key must be %d bytes, got %d", ed25519.PublicKeySize, len(key))
+	}
+
+	return ed25519.PublicKey(key), nil
+}
+
 // GetConfigPath returns the default config file path for the current platform
 func GetConfigPath() string {
 	switch runtime.GOOS {
@@ -0,0 +1,324 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/finetunelab/worker-agent/internal/executor"
+	"github.com/finetunelab/worker-agent/internal/secrets"
+	"github.com/finetunelab/worker-agent/internal/spool"
+	"github.com/finetunelab/worker-agent/internal/trading"
+	"github.com/finetunelab/worker-agent/internal/updater"
+)
+
+// Reloadable is a subsystem that can adopt a new Config without a process
+// restart. Reload should be quick and should leave the subsystem in its
+// previous state if it returns an error.
+type Reloadable interface {
+	Reload(cfg *Config) error
+}
+
+// ConfigManager holds the agent's live Config behind an atomic pointer and
+// drives the update_config hot-reload pipeline: parse, validate, diff,
+// then (unless dry-run) swap and notify every registered Reloadable.
+// Executor depends on it only through the narrower executor.ConfigReloader
+// interface, which ConfigManager's Reload method satisfies structurally.
+type ConfigManager struct {
+	path    string
+	current atomic.Pointer[Config]
+	logger  hclog.Logger
+
+	mu          sync.Mutex // serializes Reload calls and reloadables mutation
+	reloadables []Reloadable
+}
+
+// NewConfigManager creates a ConfigManager seeded with the config already
+// loaded from path.
+func NewConfigManager(cfg *Config, path string, logger hclog.Logger) *ConfigManager {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+	m := &ConfigManager{path: path, logger: logger}
+	m.current.Store(cfg)
+	return m
+}
+
+// Register subscribes r to future reloads; it's called with the new Config
+// in registration order each time Reload applies a validated update (not on
+// a dry run, nor when the update diffs to "no changes").
+func (m *ConfigManager) Register(r Reloadable) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reloadables = append(m.reloadables, r)
+}
+
+// Current returns the live Config.
+func (m *ConfigManager) Current() *Config {
+	return m.current.Load()
+}
+
+// Reload parses raw as a Config (YAML, which is also valid JSON's superset
+// for our purposes), validates it, and diffs it against the current
+// config. On a validation failure it returns the problems in
+// validationErrors and leaves live state untouched. Otherwise, unless
+// dryRun is set, it swaps the current config in and calls Reload on every
+// registered Reloadable; if any of them returns an error, the swap is
+// still honored (the new config is now authoritative) but the error is
+// returned so the caller can surface which subsystem didn't pick it up.
+// It implements executor.ConfigReloader.
+func (m *ConfigManager) Reload(ctx context.Context, raw []byte, dryRun bool) (diff string, validationErrors []string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.current.Load()
+
+	// Transparently decrypt an age-encrypted raw blob the same way
+	// LoadConfig does, so a reload picks up the file update_config (or
+	// watchConfigFile) actually sees on disk once at-rest encryption is
+	// configured, instead of handing ciphertext to yaml.Unmarshal.
+	raw, err = secrets.DecryptConfig(raw, m.path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decrypt config: %w", err)
+	}
+
+	newCfg := *old
+	if err := yaml.Unmarshal(raw, &newCfg); err != nil {
+		return "", nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	resolvedAPIKey, err := secrets.KeyringEnvProvider{}.Resolve(newCfg.APIKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve api_key: %w", err)
+	}
+	newCfg.resolvedAPIKey = resolvedAPIKey
+
+	if errs := validateForReload(&newCfg); len(errs) > 0 {
+		for _, e := range errs {
+			validationErrors = append(validationErrors, e.Error())
+		}
+		return "", validationErrors, nil
+	}
+
+	diff = diffConfig(old, &newCfg)
+
+	if dryRun || diff == "no changes" {
+		// Nothing to apply. This also breaks the save -> fsnotify Write ->
+		// reload -> save loop that watchConfigFile would otherwise drive
+		// forever: the reload triggered by our own SaveConfig below always
+		// diffs to "no changes" against the config already live in memory.
+		return diff, nil, nil
+	}
+
+	m.current.Store(&newCfg)
+
+	if m.path != "" {
+		if saveErr := newCfg.SaveConfig(m.path); saveErr != nil {
+			m.logger.Warn("failed to persist reloaded config", "error", saveErr)
+		}
+	}
+
+	var reloadErrs []error
+	for _, r := range m.reloadables {
+		if reloadErr := r.Reload(&newCfg); reloadErr != nil {
+			reloadErrs = append(reloadErrs, reloadErr)
+		}
+	}
+
+	m.logger.Info("config reloaded", "diff", diff)
+
+	if len(reloadErrs) > 0 {
+		return diff, nil, fmt.Errorf("config applied but %d subsystem(s) failed to reload: %w", len(reloadErrs), joinErrors(reloadErrs))
+	}
+	return diff, nil, nil
+}
+
+// validateForReload runs the same checks as Config.Validate plus the ones
+// that only make sense for a hot reload (e.g. a trading config whose
+// strategies must already be registered), accumulating every violation
+// instead of stopping at the first.
+func validateForReload(cfg *Config) []error {
+	var errs []error
+
+	if cfg.ResolvedAPIKey() == "" {
+		errs = append(errs, fmt.Errorf("api_key is required"))
+	}
+	if cfg.BaseURL == "" {
+		errs = append(errs, fmt.Errorf("base_url is required"))
+	}
+	if cfg.HeartbeatIntervalSeconds < 5 {
+		errs = append(errs, fmt.Errorf("heartbeat_interval_seconds must be at least 5"))
+	}
+	if cfg.MaxConcurrency < 1 {
+		errs = append(errs, fmt.Errorf("max_concurrency must be at least 1"))
+	}
+
+	if cfg.TradingEnabled && cfg.TradingConfig != "" {
+		tradingCfg, err := trading.LoadConfig(cfg.TradingConfig)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("trading_config: %w", err))
+		} else {
+			for _, sc := range tradingCfg.Strategies {
+				if _, ok := trading.LookupStrategy(sc.Name); !ok {
+					errs = append(errs, fmt.Errorf("trading_config: unknown strategy %q", sc.Name))
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// diffConfig renders the fields a hot reload can actually change as
+// "field: old -> new" lines; fields that never differ between an old and
+// new config here (e.g. WorkerID) are left out.
+func diffConfig(old, updated *Config) string {
+	var lines []string
+	line := func(field string, oldVal, newVal interface{}) {
+		if fmt.Sprintf("%v", oldVal) != fmt.Sprintf("%v", newVal) {
+			lines = append(lines, fmt.Sprintf("%s: %v -> %v", field, oldVal, newVal))
+		}
+	}
+
+	if old.APIKey != updated.APIKey {
+		lines = append(lines, "api_key: [REDACTED]")
+	}
+	line("base_url", old.BaseURL, updated.BaseURL)
+	line("heartbeat_interval_seconds", old.HeartbeatIntervalSeconds, updated.HeartbeatIntervalSeconds)
+	line("max_concurrency", old.MaxConcurrency, updated.MaxConcurrency)
+	line("capabilities", old.Capabilities, updated.Capabilities)
+	line("log_level", old.LogLevel, updated.LogLevel)
+	line("log_json", old.LogJSON, updated.LogJSON)
+	line("trading_enabled", old.TradingEnabled, updated.TradingEnabled)
+	line("trading_config", old.TradingConfig, updated.TradingConfig)
+	line("process_allowlist", old.ProcessAllowlist, updated.ProcessAllowlist)
+	line("metrics_listen_addr", old.MetricsListenAddr, updated.MetricsListenAddr)
+	line("spool_max_age_hours", old.SpoolMaxAgeHours, updated.SpoolMaxAgeHours)
+	line("spool_max_size_mb", old.SpoolMaxSizeMB, updated.SpoolMaxSizeMB)
+	line("spool_max_files", old.SpoolMaxFiles, updated.SpoolMaxFiles)
+	line("spool_sweep_interval_minutes", old.SpoolSweepIntervalMinutes, updated.SpoolSweepIntervalMinutes)
+	line("restart_drain_timeout_seconds", old.RestartDrainTimeoutSeconds, updated.RestartDrainTimeoutSeconds)
+	line("diag_max_bundle_size_mb", old.DiagMaxBundleSizeMB, updated.DiagMaxBundleSizeMB)
+	line("command_signing_public_key", old.CommandSigningPublicKey, updated.CommandSigningPublicKey)
+	line("update_public_key", old.UpdatePublicKey, updated.UpdatePublicKey)
+
+	if len(lines) == 0 {
+		return "no changes"
+	}
+
+	out := lines[0]
+	for _, l := range lines[1:] {
+		out += "\n" + l
+	}
+	return out
+}
+
+// joinErrors combines multiple reload failures into one error, e.g. for
+// CommandResult.Error.
+func joinErrors(errs []error) error {
+	msg := errs[0].Error()
+	for _, e := range errs[1:] {
+		msg += "; " + e.Error()
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+// loggerReloadable applies a reloaded config's log_level to logger. Since
+// every component logger shares its underlying level with the root logger
+// it was Named/With from (see executeSetLogLevel), this takes effect
+// agent-wide.
+type loggerReloadable struct {
+	logger hclog.Logger
+}
+
+func (r loggerReloadable) Reload(cfg *Config) error {
+	level := hclog.LevelFromString(cfg.LogLevel)
+	if level == hclog.NoLevel {
+		return fmt.Errorf("invalid log_level %q", cfg.LogLevel)
+	}
+	r.logger.SetLevel(level)
+	return nil
+}
+
+// spoolReloadable applies a reloaded config's spool retention settings to
+// spooler. The spool directory itself is fixed at Open time and can't be
+// changed by a reload.
+type spoolReloadable struct {
+	spooler *spool.Spooler
+}
+
+func (r spoolReloadable) Reload(cfg *Config) error {
+	r.spooler.Reconfigure(spool.Config{
+		MaxAge:        time.Duration(cfg.SpoolMaxAgeHours) * time.Hour,
+		MaxSize:       cfg.SpoolMaxSizeMB * 1024 * 1024,
+		MaxFiles:      cfg.SpoolMaxFiles,
+		SweepInterval: time.Duration(cfg.SpoolSweepIntervalMinutes) * time.Minute,
+	})
+	return nil
+}
+
+// signingKeyReloadable applies a reloaded config's command_signing_public_key
+// to executor, so hot-reloading it takes effect immediately instead of
+// silently diverging from what Current() reports until the next restart.
+type signingKeyReloadable struct {
+	executor *executor.Executor
+}
+
+func (r signingKeyReloadable) Reload(cfg *Config) error {
+	key, err := cfg.CommandSigningKey()
+	if err != nil {
+		return fmt.Errorf("command_signing_public_key: %w", err)
+	}
+	r.executor.SetSigningKey(key)
+	return nil
+}
+
+// updaterReloadable applies a reloaded config's update_public_key to
+// updater, so hot-reloading it takes effect immediately instead of
+// silently diverging from what Current() reports until the next restart.
+type updaterReloadable struct {
+	updater *updater.Updater
+}
+
+func (r updaterReloadable) Reload(cfg *Config) error {
+	key, err := cfg.UpdateSigningKey()
+	if err != nil {
+		return fmt.Errorf("update_public_key: %w", err)
+	}
+	r.updater.SetPublicKey(key)
+	return nil
+}
+
+// tradingReloadable starts, stops, or restarts the trading service to
+// match a reloaded config's trading_enabled/trading_config.
+type tradingReloadable struct {
+	ctx context.Context
+	ts  *executor.TradingService
+}
+
+func (r tradingReloadable) Reload(cfg *Config) error {
+	running := r.ts.IsRunning()
+
+	if !cfg.TradingEnabled {
+		if running {
+			return r.ts.Stop(r.ctx)
+		}
+		return nil
+	}
+
+	if running && r.ts.ConfigPath() == cfg.TradingConfig {
+		return nil
+	}
+
+	if running {
+		if err := r.ts.Stop(r.ctx); err != nil {
+			return fmt.Errorf("failed to stop trading for reload: %w", err)
+		}
+	}
+	return r.ts.Start(r.ctx, cfg.TradingConfig)
+}
@@ -0,0 +1,277 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"gopkg.in/yaml.v3"
+
+	"github.com/finetunelab/worker-agent/internal/executor"
+	"github.com/finetunelab/worker-agent/internal/updater"
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+func testBaseConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.APIKey = "test-key"
+	return cfg
+}
+
+func TestConfigManagerReloadRejectsInvalidConfigAndLeavesLiveConfigUntouched(t *testing.T) {
+	cfg := testBaseConfig()
+	m := NewConfigManager(cfg, "", nil)
+
+	raw := []byte("api_key: test-key\nbase_url: \"\"\nheartbeat_interval_seconds: 30\nmax_concurrency: 1\n")
+	diff, validationErrors, err := m.Reload(context.Background(), raw, false)
+	if err != nil {
+		t.Fatalf("Reload returned unexpected error: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("Reload with validation errors returned diff %q, want empty", diff)
+	}
+	if len(validationErrors) == 0 {
+		t.Fatal("Reload with empty base_url: want validation error, got none")
+	}
+	if got := m.Current().BaseURL; got != cfg.BaseURL {
+		t.Errorf("Current().BaseURL = %q after failed reload, want unchanged %q", got, cfg.BaseURL)
+	}
+}
+
+func TestConfigManagerReloadDryRunDoesNotApply(t *testing.T) {
+	cfg := testBaseConfig()
+	m := NewConfigManager(cfg, "", nil)
+
+	updated := *cfg
+	updated.MaxConcurrency = cfg.MaxConcurrency + 4
+	raw, err := yaml.Marshal(&updated)
+	if err != nil {
+		t.Fatalf("yaml.Marshal: %v", err)
+	}
+
+	diff, validationErrors, err := m.Reload(context.Background(), raw, true)
+	if err != nil {
+		t.Fatalf("Reload returned unexpected error: %v", err)
+	}
+	if len(validationErrors) != 0 {
+		t.Fatalf("Reload validationErrors = %v, want none", validationErrors)
+	}
+	if diff == "no changes" || diff == "" {
+		t.Errorf("dry-run diff = %q, want a description of the max_concurrency change", diff)
+	}
+	if got := m.Current().MaxConcurrency; got != cfg.MaxConcurrency {
+		t.Errorf("Current().MaxConcurrency = %d after dry run, want unchanged %d", got, cfg.MaxConcurrency)
+	}
+}
+
+func TestConfigManagerReloadAppliesValidChange(t *testing.T) {
+	cfg := testBaseConfig()
+	m := NewConfigManager(cfg, "", nil)
+
+	updated := *cfg
+	updated.MaxConcurrency = cfg.MaxConcurrency + 4
+	raw, err := yaml.Marshal(&updated)
+	if err != nil {
+		t.Fatalf("yaml.Marshal: %v", err)
+	}
+
+	if _, validationErrors, err := m.Reload(context.Background(), raw, false); err != nil || len(validationErrors) != 0 {
+		t.Fatalf("Reload() validationErrors=%v err=%v, want success", validationErrors, err)
+	}
+
+	if got := m.Current().MaxConcurrency; got != updated.MaxConcurrency {
+		t.Errorf("Current().MaxConcurrency = %d, want %d", got, updated.MaxConcurrency)
+	}
+}
+
+// TestSigningKeyReloadableTakesEffectImmediately guards against the live
+// config and the enforced signing key silently diverging: hot-reloading
+// command_signing_public_key via update_config must change what Execute
+// actually enforces, not just what Current() reports.
+func TestSigningKeyReloadableTakesEffectImmediately(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	exec := executor.New(context.Background(), nil, nil, nil, nil, nil, nil, "", 0, nil)
+
+	cmd := api.Command{
+		ID:             "cmd-1",
+		CommandType:    api.CommandSetLogLevel,
+		Params:         map[string]interface{}{"level": "info"},
+		TimeoutSeconds: 5,
+	}
+
+	if result := exec.Execute(context.Background(), cmd); result.Status != "completed" {
+		t.Fatalf("Execute before reload: status = %q, want completed (no signing key configured yet)", result.Status)
+	}
+
+	cfg := testBaseConfig()
+	cfg.CommandSigningPublicKey = base64.StdEncoding.EncodeToString(pub)
+
+	r := signingKeyReloadable{executor: exec}
+	if err := r.Reload(cfg); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	result := exec.Execute(context.Background(), cmd)
+	if result.Status != "error" {
+		t.Errorf("Execute after reload: status = %q, want error (unsigned command should now be rejected)", result.Status)
+	}
+}
+
+// TestConfigManagerReloadAppliesSigningKeyOnlyChange guards against
+// diffConfig missing command_signing_public_key (or the other key
+// fields, api_key and update_public_key): a
+// reload that changes only one of those must still diff as a change, not
+// "no changes", or ConfigManager.Reload returns early and the new key
+// never reaches Current() or any registered Reloadable.
+func TestConfigManagerReloadAppliesSigningKeyOnlyChange(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	cfg := testBaseConfig()
+	m := NewConfigManager(cfg, "", nil)
+
+	updated := *cfg
+	updated.CommandSigningPublicKey = base64.StdEncoding.EncodeToString(pub)
+	raw, err := yaml.Marshal(&updated)
+	if err != nil {
+		t.Fatalf("yaml.Marshal: %v", err)
+	}
+
+	diff, validationErrors, err := m.Reload(context.Background(), raw, false)
+	if err != nil {
+		t.Fatalf("Reload returned unexpected error: %v", err)
+	}
+	if len(validationErrors) != 0 {
+		t.Fatalf("Reload validationErrors = %v, want none", validationErrors)
+	}
+	if diff == "no changes" {
+		t.Fatal("Reload diff = \"no changes\" for a signing-key-only change, want a reported change")
+	}
+
+	if got := m.Current().CommandSigningPublicKey; got != updated.CommandSigningPublicKey {
+		t.Errorf("Current().CommandSigningPublicKey = %q after reload, want %q", got, updated.CommandSigningPublicKey)
+	}
+}
+
+// TestUpdaterReloadableTakesEffectImmediately guards against the live
+// config and the key Apply actually verifies releases against silently
+// diverging: hot-reloading update_public_key via update_config must
+// change what Apply enforces, not just what Current() reports.
+func TestUpdaterReloadableTakesEffectImmediately(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	binaryData := []byte("fake release binary")
+	sig := ed25519.Sign(priv, binaryData)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binaryData)
+	}))
+	defer server.Close()
+
+	manifest := &api.ReleaseManifest{
+		Version:   "2.0.0",
+		URL:       server.URL + "/worker-agent",
+		Signature: base64.StdEncoding.EncodeToString(sig),
+	}
+
+	binPath := filepath.Join(t.TempDir(), "worker-agent")
+	if err := os.WriteFile(binPath, []byte("old binary"), 0755); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	upd, err := updater.New(updater.Config{
+		BaseURL:        server.URL,
+		CurrentVersion: "1.0.0",
+		BinaryPath:     binPath,
+	}, nil)
+	if err != nil {
+		t.Fatalf("updater.New: %v", err)
+	}
+
+	if err := upd.Apply(context.Background(), manifest); err == nil {
+		t.Fatal("Apply before reload: want error (no public key configured yet)")
+	}
+
+	cfg := testBaseConfig()
+	cfg.UpdatePublicKey = base64.StdEncoding.EncodeToString(pub)
+
+	r := updaterReloadable{updater: upd}
+	if err := r.Reload(cfg); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if err := upd.Apply(context.Background(), manifest); err != nil {
+		t.Errorf("Apply after reload: %v, want success (release is signed by the reloaded key)", err)
+	}
+}
+
+// TestConfigManagerReloadDecryptsAgeEncryptedConfig guards against Reload
+// handing ciphertext straight to yaml.Unmarshal once at-rest encryption
+// (chunk0-6) is configured: an age-encrypted blob, of the kind SaveConfig
+// writes to disk when an .age-recipients file is present, must still be
+// accepted by Reload (decrypted with the .age-identity file next to the
+// config), the way LoadConfig accepts it at startup.
+func TestConfigManagerReloadDecryptsAgeEncryptedConfig(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity: %v", err)
+	}
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	identityPath := configPath + ".age-identity"
+	if err := os.WriteFile(identityPath, []byte(identity.String()+"\n"), 0600); err != nil {
+		t.Fatalf("WriteFile identity: %v", err)
+	}
+
+	cfg := testBaseConfig()
+	m := NewConfigManager(cfg, configPath, nil)
+
+	updated := *cfg
+	updated.MaxConcurrency = cfg.MaxConcurrency + 4
+	plain, err := yaml.Marshal(&updated)
+	if err != nil {
+		t.Fatalf("yaml.Marshal: %v", err)
+	}
+
+	var encrypted bytes.Buffer
+	w, err := age.Encrypt(&encrypted, identity.Recipient())
+	if err != nil {
+		t.Fatalf("age.Encrypt: %v", err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("write ciphertext: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close ciphertext writer: %v", err)
+	}
+
+	diff, validationErrors, err := m.Reload(context.Background(), encrypted.Bytes(), false)
+	if err != nil {
+		t.Fatalf("Reload returned unexpected error: %v", err)
+	}
+	if len(validationErrors) != 0 {
+		t.Fatalf("Reload validationErrors = %v, want none", validationErrors)
+	}
+	if diff == "no changes" || diff == "" {
+		t.Errorf("diff = %q, want a description of the max_concurrency change", diff)
+	}
+	if got := m.Current().MaxConcurrency; got != updated.MaxConcurrency {
+		t.Errorf("Current().MaxConcurrency = %d, want %d", got, updated.MaxConcurrency)
+	}
+}
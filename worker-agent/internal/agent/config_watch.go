@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"os"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchConfigFile watches the on-disk config file and runs it through the
+// same hot-reload pipeline as an update_config command whenever it
+// changes, so an operator editing the file directly doesn't need to send
+// a command to pick it up. It returns once ctx is canceled.
+func (a *Agent) watchConfigFile() {
+	defer a.wg.Done()
+
+	path := a.configPath
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		a.logger.Warn("failed to start config file watcher", "error", err)
+		return
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(path); err != nil {
+		a.logger.Warn("failed to watch config file", "path", path, "error", err)
+		return
+	}
+
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// Editors commonly replace the file (write-rename) rather than
+			// writing in place, which drops the original inode from the
+			// watch; re-adding it on every event keeps the watch alive
+			// either way.
+			if err := watcher.Add(path); err != nil {
+				a.logger.Warn("failed to re-watch config file", "path", path, "error", err)
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				a.logger.Warn("failed to read changed config file", "path", path, "error", err)
+				continue
+			}
+
+			diff, validationErrors, err := a.configManager.Reload(a.ctx, raw, false)
+			if len(validationErrors) > 0 {
+				a.logger.Warn("config file change failed validation, ignoring", "errors", validationErrors)
+				continue
+			}
+			if err != nil {
+				a.logger.Error("failed to reload config file", "error", err)
+				continue
+			}
+			a.logger.Info("reloaded config from disk", "diff", diff)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			a.logger.Warn("config file watcher error", "error", err)
+		}
+	}
+}
@@ -2,30 +2,90 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/finetunelab/worker-agent/internal/logging"
+	"github.com/finetunelab/worker-agent/internal/outbox"
 	"github.com/finetunelab/worker-agent/pkg/api"
 )
 
+const (
+	// outboxKindCommandResult and outboxKindMetrics identify the payload
+	// type of a queued outbox item.
+	outboxKindCommandResult = "command_result"
+	outboxKindMetrics       = "metrics"
+
+	// defaultOutboxMaxBytes bounds the on-disk outbox before the oldest
+	// queued items start being dropped.
+	defaultOutboxMaxBytes = 10 * 1024 * 1024
+)
+
 // HTTPClient handles HTTP communication with the SaaS API
 type HTTPClient struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+	outbox     *outbox.Outbox
+	logger     hclog.Logger
 }
 
-// NewHTTPClient creates a new HTTP client
-func NewHTTPClient(baseURL, apiKey string) *HTTPClient {
-	return &HTTPClient{
+// NewHTTPClient creates a new HTTP client. outboxPath is the file used to
+// durably queue command results and metrics batches while the SaaS is
+// unreachable; results are replayed from it on every subsequent restart.
+func NewHTTPClient(baseURL, apiKey, outboxPath string, logger hclog.Logger) (*HTTPClient, error) {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+
+	c := &HTTPClient{
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		logger: logger,
+	}
+
+	ob, err := outbox.Open(outboxPath, defaultOutboxMaxBytes, c.deliverQueuedItem)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox: %w", err)
+	}
+	c.outbox = ob
+
+	return c, nil
+}
+
+// RunOutbox drains the durable outbox until ctx is canceled. It should be
+// run in its own goroutine for the lifetime of the agent.
+func (c *HTTPClient) RunOutbox(ctx context.Context) {
+	c.outbox.Run(ctx)
+}
+
+// deliverQueuedItem is the outbox.SendFunc used to replay queued items.
+func (c *HTTPClient) deliverQueuedItem(ctx context.Context, kind string, payload json.RawMessage) error {
+	switch kind {
+	case outboxKindCommandResult:
+		var result api.CommandResult
+		if err := json.Unmarshal(payload, &result); err != nil {
+			return fmt.Errorf("failed to unmarshal queued command result: %w", err)
+		}
+		return c.sendCommandResult(&result)
+	case outboxKindMetrics:
+		var batch api.MetricsBatch
+		if err := json.Unmarshal(payload, &batch); err != nil {
+			return fmt.Errorf("failed to unmarshal queued metrics batch: %w", err)
+		}
+		return c.sendMetrics(&batch)
+	default:
+		c.logger.Warn("dropping queued outbox item of unknown kind", "kind", kind)
+		return nil
 	}
 }
 
@@ -100,15 +160,94 @@ func (c *HTTPClient) Heartbeat(workerID string, req *api.HeartbeatRequest) (*api
 	return &heartbeatResp, nil
 }
 
-// SendMetrics sends metrics to the SaaS (future implementation)
+// SendMetrics sends a metrics batch to the SaaS. On failure it is queued
+// in the durable outbox and retried in the background so no sample is
+// lost across a network outage.
 func (c *HTTPClient) SendMetrics(batch *api.MetricsBatch) error {
-	// TODO: Implement metrics ingestion endpoint call
+	if err := c.sendMetrics(batch); err != nil {
+		c.logger.Warn("failed to send metrics, queuing for retry", "error", err)
+		return c.outbox.Enqueue(outboxKindMetrics, batch)
+	}
+	return nil
+}
+
+// sendMetrics performs the actual metrics ingestion request.
+func (c *HTTPClient) sendMetrics(batch *api.MetricsBatch) error {
+	url := fmt.Sprintf("%s/api/workers/%s/metrics", c.baseURL, batch.WorkerID)
+
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metrics batch: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-API-Key", c.apiKey)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("send metrics failed: %s (status %d)", string(body), resp.StatusCode)
+	}
+
 	return nil
 }
 
-// SendCommandResult sends command execution result
+// SendLogs pushes a batch of recent log records to the SaaS for remote
+// triage, e.g. a snapshot of the agent's log ring buffer.
+func (c *HTTPClient) SendLogs(workerID string, records []logging.Record) error {
+	url := fmt.Sprintf("%s/api/workers/%s/logs", c.baseURL, workerID)
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to marshal log records: %w", err)
+	}
+
+	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("X-API-Key", c.apiKey)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("send logs failed: %s (status %d)", string(body), resp.StatusCode)
+	}
+
+	return nil
+}
+
+// SendCommandResult sends a command execution result to the SaaS. On
+// failure it is queued in the durable outbox and retried in the
+// background, since command outcomes must eventually be confirmed.
 func (c *HTTPClient) SendCommandResult(commandID string, result *api.CommandResult) error {
-	url := fmt.Sprintf("%s/api/workers/commands/%s/result", c.baseURL, commandID)
+	if err := c.sendCommandResult(result); err != nil {
+		c.logger.Warn("failed to send command result, queuing for retry", "command_id", commandID, "error", err)
+		return c.outbox.Enqueue(outboxKindCommandResult, result)
+	}
+	return nil
+}
+
+// sendCommandResult performs the actual command result delivery request.
+func (c *HTTPClient) sendCommandResult(result *api.CommandResult) error {
+	url := fmt.Sprintf("%s/api/workers/commands/%s/result", c.baseURL, result.CommandID)
 
 	body, err := json.Marshal(result)
 	if err != nil {
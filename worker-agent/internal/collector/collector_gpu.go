@@ -0,0 +1,64 @@
+//go:build gpu
+
+package collector
+
+import (
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+// collectGPU fills GPU telemetry via NVML. Built only with `-tags gpu` on
+// hosts where the NVIDIA driver/NVML library is present; see the no-op
+// stub in collector_gpu_stub.go for the default build.
+func (mc *MetricsCollector) collectGPU(metrics *api.MetricsSnapshot) {
+	if ret := nvml.Init(); ret != nvml.SUCCESS {
+		mc.logger.Warn("NVML init failed", "error", nvml.ErrorString(ret))
+		return
+	}
+	defer nvml.Shutdown()
+
+	count, ret := nvml.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		mc.logger.Warn("NVML device count failed", "error", nvml.ErrorString(ret))
+		return
+	}
+
+	for i := 0; i < count; i++ {
+		device, ret := nvml.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			mc.logger.Warn("NVML device handle failed", "index", i, "error", nvml.ErrorString(ret))
+			continue
+		}
+
+		name, _ := device.GetName()
+
+		util, ret := device.GetUtilizationRates()
+		var utilPercent float64
+		if ret == nvml.SUCCESS {
+			utilPercent = float64(util.Gpu)
+		}
+
+		memInfo, ret := device.GetMemoryInfo()
+		var usedMB, totalMB uint64
+		if ret == nvml.SUCCESS {
+			usedMB = memInfo.Used / 1024 / 1024
+			totalMB = memInfo.Total / 1024 / 1024
+		}
+
+		tempC, ret := device.GetTemperature(nvml.TEMPERATURE_GPU)
+		var temperature float64
+		if ret == nvml.SUCCESS {
+			temperature = float64(tempC)
+		}
+
+		metrics.GPUs = append(metrics.GPUs, api.GPUMetric{
+			Index:              i,
+			Name:               name,
+			UtilizationPercent: utilPercent,
+			MemoryUsedMB:       usedMB,
+			MemoryTotalMB:      totalMB,
+			TemperatureC:       temperature,
+		})
+	}
+}
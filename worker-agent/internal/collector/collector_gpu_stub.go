@@ -0,0 +1,10 @@
+//go:build !gpu
+
+package collector
+
+import "github.com/finetunelab/worker-agent/pkg/api"
+
+// collectGPU is a no-op on the default build, which does not link against
+// NVML. Build with `-tags gpu` on hosts that expose NVIDIA GPUs to enable
+// real telemetry via collector_gpu.go.
+func (mc *MetricsCollector) collectGPU(metrics *api.MetricsSnapshot) {}
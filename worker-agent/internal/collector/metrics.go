@@ -1,98 +1,233 @@
-package collector
-
-import (
-	"log"
-	"time"
-
-	"github.com/finetunelab/worker-agent/pkg/api"
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/disk"
-	"github.com/shirou/gopsutil/v3/mem"
-	"github.com/shirou/gopsutil/v3/net"
-)
-
-// MetricsCollector collects system metrics
-type MetricsCollector struct {
-	lastNetStats *net.IOCountersStat
-	lastNetTime  time.Time
-}
-
-// NewMetricsCollector creates a new metrics collector
-func NewMetricsCollector() *MetricsCollector {
-	return &MetricsCollector{}
-}
-
-// Collect collects current system metrics
-func (mc *MetricsCollector) Collect() (*api.MetricsSnapshot, error) {
-	metrics := &api.MetricsSnapshot{}
-
-	// Collect CPU percentage
-	cpuPercent, err := cpu.Percent(time.Second, false)
-	if err != nil {
-		log.Printf("[Metrics] Warning: Failed to get CPU: %v", err)
-	} else if len(cpuPercent) > 0 {
-		metrics.CPUPercent = cpuPercent[0]
-	}
-
-	// Collect memory
-	vmem, err := mem.VirtualMemory()
-	if err != nil {
-		log.Printf("[Metrics] Warning: Failed to get memory: %v", err)
-	} else {
-		metrics.MemoryUsedMB = vmem.Used / 1024 / 1024
-		metrics.MemoryTotalMB = vmem.Total / 1024 / 1024
-	}
-
-	// Collect disk usage (root partition)
-	diskStats, err := disk.Usage("/")
-	if err != nil {
-		log.Printf("[Metrics] Warning: Failed to get disk: %v", err)
-	} else {
-		_ = diskStats // Store in metrics if needed
-		// Note: api.MetricsSnapshot doesn't have disk fields yet
-		// TODO: Add disk fields to MetricsSnapshot type
-	}
-
-	// Collect network stats
-	netStats, err := net.IOCounters(false)
-	if err != nil {
-		log.Printf("[Metrics] Warning: Failed to get network: %v", err)
-	} else if len(netStats) > 0 {
-		currentStats := netStats[0]
-
-		// Calculate delta if we have previous stats
-		if mc.lastNetStats != nil {
-			timeDelta := time.Since(mc.lastNetTime).Seconds()
-			if timeDelta > 0 {
-				sentDelta := currentStats.BytesSent - mc.lastNetStats.BytesSent
-				recvDelta := currentStats.BytesRecv - mc.lastNetStats.BytesRecv
-
-				// Convert to MB/s, then multiply by time to get total MB in period
-				_ = float64(sentDelta) / 1024 / 1024 / timeDelta
-				_ = float64(recvDelta) / 1024 / 1024 / timeDelta
-
-				// Note: api.MetricsSnapshot doesn't have network fields yet
-				// TODO: Add network fields to MetricsSnapshot type
-			}
-		}
-
-		// Store current stats for next collection
-		mc.lastNetStats = &currentStats
-		mc.lastNetTime = time.Now()
-	}
-
-	return metrics, nil
-}
-
-// CollectWithTrading collects metrics including trading status
-func (mc *MetricsCollector) CollectWithTrading(tradingStatus string, activeTrades int) (*api.MetricsSnapshot, error) {
-	metrics, err := mc.Collect()
-	if err != nil {
-		return nil, err
-	}
-
-	metrics.TradingStatus = tradingStatus
-	metrics.ActiveTrades = activeTrades
-
-	return metrics, nil
-}
+package collector
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// MetricsCollector collects system metrics
+type MetricsCollector struct {
+	// processNames is the allowlist of process names (as reported by the
+	// OS, e.g. the trading engine binary) to report per-process metrics
+	// for. An empty allowlist collects nothing process-specific.
+	processNames map[string]bool
+
+	// mu guards the delta-tracking fields below and last, since Collect
+	// runs on the heartbeat goroutine while Last is read concurrently by
+	// the Prometheus HTTP handler.
+	mu            sync.Mutex
+	lastNetStats  *net.IOCountersStat
+	lastNetTime   time.Time
+	lastDiskStats *disk.IOCountersStat
+	lastDiskTime  time.Time
+
+	last *api.MetricsSnapshot
+
+	logger hclog.Logger
+}
+
+// NewMetricsCollector creates a new metrics collector. processNames is the
+// allowlist of process names to report PerProcess metrics for.
+func NewMetricsCollector(processNames []string, logger hclog.Logger) *MetricsCollector {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+
+	allowlist := make(map[string]bool, len(processNames))
+	for _, name := range processNames {
+		allowlist[name] = true
+	}
+
+	return &MetricsCollector{processNames: allowlist, logger: logger}
+}
+
+// Collect collects current system metrics
+func (mc *MetricsCollector) Collect() (*api.MetricsSnapshot, error) {
+	metrics := &api.MetricsSnapshot{}
+
+	// Collect CPU percentage
+	cpuPercent, err := cpu.Percent(time.Second, false)
+	if err != nil {
+		mc.logger.Warn("failed to get CPU", "error", err)
+	} else if len(cpuPercent) > 0 {
+		metrics.CPUPercent = cpuPercent[0]
+	}
+
+	// Collect memory
+	vmem, err := mem.VirtualMemory()
+	if err != nil {
+		mc.logger.Warn("failed to get memory", "error", err)
+	} else {
+		metrics.MemoryUsedMB = vmem.Used / 1024 / 1024
+		metrics.MemoryTotalMB = vmem.Total / 1024 / 1024
+	}
+
+	mc.collectDisk(metrics)
+	mc.collectNetwork(metrics)
+	mc.collectLoadAvg(metrics)
+	mc.collectPerProcess(metrics)
+	mc.collectGPU(metrics)
+
+	mc.mu.Lock()
+	mc.last = metrics
+	mc.mu.Unlock()
+
+	return metrics, nil
+}
+
+// collectDisk fills root partition usage and read/write throughput summed
+// across all disks.
+func (mc *MetricsCollector) collectDisk(metrics *api.MetricsSnapshot) {
+	diskUsage, err := disk.Usage("/")
+	if err != nil {
+		mc.logger.Warn("failed to get disk usage", "error", err)
+	} else {
+		metrics.DiskUsedGB = float64(diskUsage.Used) / 1024 / 1024 / 1024
+		metrics.DiskTotalGB = float64(diskUsage.Total) / 1024 / 1024 / 1024
+	}
+
+	ioStats, err := disk.IOCounters()
+	if err != nil || len(ioStats) == 0 {
+		if err != nil {
+			mc.logger.Warn("failed to get disk IO counters", "error", err)
+		}
+		return
+	}
+
+	// Aggregate across all reported disks.
+	var combined disk.IOCountersStat
+	for _, stat := range ioStats {
+		combined.ReadBytes += stat.ReadBytes
+		combined.WriteBytes += stat.WriteBytes
+	}
+
+	mc.mu.Lock()
+	if mc.lastDiskStats != nil {
+		timeDelta := time.Since(mc.lastDiskTime).Seconds()
+		if timeDelta > 0 {
+			readDelta := combined.ReadBytes - mc.lastDiskStats.ReadBytes
+			writeDelta := combined.WriteBytes - mc.lastDiskStats.WriteBytes
+			metrics.DiskReadMBps = float64(readDelta) / 1024 / 1024 / timeDelta
+			metrics.DiskWriteMBps = float64(writeDelta) / 1024 / 1024 / timeDelta
+		}
+	}
+
+	mc.lastDiskStats = &combined
+	mc.lastDiskTime = time.Now()
+	mc.mu.Unlock()
+}
+
+// collectNetwork fills sent/received throughput since the last collection.
+func (mc *MetricsCollector) collectNetwork(metrics *api.MetricsSnapshot) {
+	netStats, err := net.IOCounters(false)
+	if err != nil {
+		mc.logger.Warn("failed to get network", "error", err)
+		return
+	}
+	if len(netStats) == 0 {
+		return
+	}
+
+	currentStats := netStats[0]
+
+	mc.mu.Lock()
+	if mc.lastNetStats != nil {
+		timeDelta := time.Since(mc.lastNetTime).Seconds()
+		if timeDelta > 0 {
+			sentDelta := currentStats.BytesSent - mc.lastNetStats.BytesSent
+			recvDelta := currentStats.BytesRecv - mc.lastNetStats.BytesRecv
+
+			metrics.NetSentMBps = float64(sentDelta) / 1024 / 1024 / timeDelta
+			metrics.NetRecvMBps = float64(recvDelta) / 1024 / 1024 / timeDelta
+		}
+	}
+
+	mc.lastNetStats = &currentStats
+	mc.lastNetTime = time.Now()
+	mc.mu.Unlock()
+}
+
+// collectLoadAvg fills the 1/5/15 minute load averages (no-op on Windows).
+func (mc *MetricsCollector) collectLoadAvg(metrics *api.MetricsSnapshot) {
+	avg, err := load.Avg()
+	if err != nil {
+		mc.logger.Warn("failed to get load average", "error", err)
+		return
+	}
+
+	metrics.LoadAvg1 = avg.Load1
+	metrics.LoadAvg5 = avg.Load5
+	metrics.LoadAvg15 = avg.Load15
+}
+
+// collectPerProcess fills per-process CPU/memory metrics for every running
+// process whose name is in the configured allowlist.
+func (mc *MetricsCollector) collectPerProcess(metrics *api.MetricsSnapshot) {
+	if len(mc.processNames) == 0 {
+		return
+	}
+
+	pids, err := process.Pids()
+	if err != nil {
+		mc.logger.Warn("failed to list processes", "error", err)
+		return
+	}
+
+	for _, pid := range pids {
+		proc, err := process.NewProcess(pid)
+		if err != nil {
+			continue
+		}
+
+		name, err := proc.Name()
+		if err != nil || !mc.processNames[name] {
+			continue
+		}
+
+		cpuPct, _ := proc.CPUPercent()
+		memInfo, err := proc.MemoryInfo()
+		var rssMB uint64
+		if err == nil && memInfo != nil {
+			rssMB = memInfo.RSS / 1024 / 1024
+		}
+
+		metrics.PerProcess = append(metrics.PerProcess, api.ProcessMetric{
+			PID:         pid,
+			Name:        name,
+			CPUPercent:  cpuPct,
+			MemoryRSSMB: rssMB,
+		})
+	}
+}
+
+// CollectWithTrading collects metrics including trading status
+func (mc *MetricsCollector) CollectWithTrading(tradingStatus string, activeTrades int) (*api.MetricsSnapshot, error) {
+	metrics, err := mc.Collect()
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.TradingStatus = tradingStatus
+	metrics.ActiveTrades = activeTrades
+
+	return metrics, nil
+}
+
+// Last returns the most recently collected snapshot, or nil if Collect has
+// not been called yet. Used by the Prometheus exposition handler so
+// scrapes don't force an extra collection.
+func (mc *MetricsCollector) Last() *api.MetricsSnapshot {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return mc.last
+}
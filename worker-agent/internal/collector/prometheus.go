@@ -0,0 +1,93 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+// StartPrometheusListener serves the most recently collected snapshot in
+// Prometheus text exposition format on addr (e.g. "127.0.0.1:9273") under
+// /metrics, alongside the existing JSON MetricsBatch reporting to the
+// SaaS. It blocks until ctx is canceled and should be run in its own
+// goroutine.
+func StartPrometheusListener(ctx context.Context, addr string, mc *MetricsCollector) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		writePrometheusMetrics(w, mc.Last())
+	})
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case <-ctx.Done():
+		mc.logger.Info("stopping Prometheus listener")
+		return server.Shutdown(context.Background())
+	case err := <-errCh:
+		if err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("prometheus listener failed: %w", err)
+		}
+		return nil
+	}
+}
+
+// writePrometheusMetrics renders snapshot in the Prometheus text exposition
+// format. A nil snapshot (no collection has happened yet) renders nothing.
+func writePrometheusMetrics(w http.ResponseWriter, snapshot *api.MetricsSnapshot) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	if snapshot == nil {
+		return
+	}
+
+	gauge(w, "worker_agent_cpu_percent", "Current CPU utilization percentage", snapshot.CPUPercent)
+	gauge(w, "worker_agent_memory_used_mb", "Memory used in MB", float64(snapshot.MemoryUsedMB))
+	gauge(w, "worker_agent_memory_total_mb", "Total memory in MB", float64(snapshot.MemoryTotalMB))
+	gauge(w, "worker_agent_disk_used_gb", "Disk used in GB", snapshot.DiskUsedGB)
+	gauge(w, "worker_agent_disk_total_gb", "Disk total in GB", snapshot.DiskTotalGB)
+	gauge(w, "worker_agent_disk_read_mbps", "Disk read throughput in MB/s", snapshot.DiskReadMBps)
+	gauge(w, "worker_agent_disk_write_mbps", "Disk write throughput in MB/s", snapshot.DiskWriteMBps)
+	gauge(w, "worker_agent_net_sent_mbps", "Network send throughput in MB/s", snapshot.NetSentMBps)
+	gauge(w, "worker_agent_net_recv_mbps", "Network receive throughput in MB/s", snapshot.NetRecvMBps)
+	gauge(w, "worker_agent_load_avg1", "1 minute load average", snapshot.LoadAvg1)
+	gauge(w, "worker_agent_load_avg5", "5 minute load average", snapshot.LoadAvg5)
+	gauge(w, "worker_agent_load_avg15", "15 minute load average", snapshot.LoadAvg15)
+	gauge(w, "worker_agent_active_trades", "Active trades reported by the trading service", float64(snapshot.ActiveTrades))
+
+	for _, proc := range snapshot.PerProcess {
+		labels := fmt.Sprintf(`{pid="%d",name=%q}`, proc.PID, proc.Name)
+		gaugeLabeled(w, "worker_agent_process_cpu_percent", "Per-process CPU utilization percentage", labels, proc.CPUPercent)
+		gaugeLabeled(w, "worker_agent_process_memory_rss_mb", "Per-process resident memory in MB", labels, float64(proc.MemoryRSSMB))
+	}
+
+	for _, gpu := range snapshot.GPUs {
+		labels := fmt.Sprintf(`{index="%d",name=%q}`, gpu.Index, gpu.Name)
+		gaugeLabeled(w, "worker_agent_gpu_utilization_percent", "GPU utilization percentage", labels, gpu.UtilizationPercent)
+		gaugeLabeled(w, "worker_agent_gpu_memory_used_mb", "GPU memory used in MB", labels, float64(gpu.MemoryUsedMB))
+		gaugeLabeled(w, "worker_agent_gpu_memory_total_mb", "GPU memory total in MB", labels, float64(gpu.MemoryTotalMB))
+		gaugeLabeled(w, "worker_agent_gpu_temperature_c", "GPU temperature in Celsius", labels, gpu.TemperatureC)
+	}
+}
+
+func gauge(w http.ResponseWriter, name, help string, value float64) {
+	gaugeLabeled(w, name, help, "", value)
+}
+
+func gaugeLabeled(w http.ResponseWriter, name, help, labels string, value float64) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(&b, "# TYPE %s gauge\n", name)
+	fmt.Fprintf(&b, "%s%s %v\n", name, labels, value)
+	w.Write([]byte(b.String()))
+}
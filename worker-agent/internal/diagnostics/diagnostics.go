@@ -0,0 +1,277 @@
+// Package diagnostics assembles a point-in-time snapshot of the running
+// agent - system info, Go runtime stats, a goroutine dump, an optional CPU
+// profile, command history, and trading status - into a single gzipped tar
+// bundle for support requests, mirroring the diagnostics bundles produced by
+// agents like Consul and Coder.
+package diagnostics
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// cpuProfileDuration is how long Collect samples the CPU when a caller asks
+// for a profile.
+const cpuProfileDuration = 30 * time.Second
+
+// defaultMaxBundleSizeMB is the size cap applied when Config.MaxSizeMB is
+// unset.
+const defaultMaxBundleSizeMB = 50
+
+// Config configures a Collector.
+type Config struct {
+	// Dir is the directory bundles are written to.
+	Dir string
+	// MaxSizeMB caps the total size of a bundle; entries that would push
+	// it over the cap are skipped rather than truncated. <= 0 uses
+	// defaultMaxBundleSizeMB.
+	MaxSizeMB int64
+}
+
+// Sources gathers the diagnostics a Collector has no direct access to; the
+// caller (the executor) fills this in from its own state before calling
+// Collect.
+type Sources struct {
+	// CommandHistory is marshaled as-is into command_history.json; pass
+	// the spool's records, or an explanatory string if no spool is
+	// configured.
+	CommandHistory interface{}
+	// Trading is marshaled as-is into trading.json.
+	Trading interface{}
+	// RecentLogs is appended to logs.txt, oldest first.
+	RecentLogs []LogRecord
+}
+
+// LogRecord is one captured log line, matching internal/logging.Record
+// without importing it (diagnostics stays decoupled from the logging ring
+// buffer implementation).
+type LogRecord struct {
+	Time time.Time
+	Line string
+}
+
+// Collector builds diagnostics bundles under Config.Dir.
+type Collector struct {
+	cfg    Config
+	logger hclog.Logger
+}
+
+// New creates a Collector, creating Dir if it doesn't exist.
+func New(cfg Config, logger hclog.Logger) (*Collector, error) {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("diagnostics: Dir is required")
+	}
+	if cfg.MaxSizeMB <= 0 {
+		cfg.MaxSizeMB = defaultMaxBundleSizeMB
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create diagnostics directory: %w", err)
+	}
+
+	return &Collector{cfg: cfg, logger: logger}, nil
+}
+
+// Collect gathers a diagnostics bundle and writes it as a gzipped tar file
+// under Config.Dir, returning its path. If cpuProfile is true, Collect
+// blocks for up to cpuProfileDuration (or until ctx is canceled) sampling a
+// CPU profile.
+func (c *Collector) Collect(ctx context.Context, sources Sources, cpuProfile bool) (string, error) {
+	path := filepath.Join(c.cfg.Dir, fmt.Sprintf("diag-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z")))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create bundle file: %w", err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	maxBytes := c.cfg.MaxSizeMB * 1024 * 1024
+	var written int64
+	var dropped []string
+
+	addEntry := func(name string, data []byte) {
+		if written+int64(len(data)) > maxBytes {
+			dropped = append(dropped, name)
+			return
+		}
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			c.logger.Warn("failed to write bundle entry header", "entry", name, "error", err)
+			return
+		}
+		if _, err := tw.Write(data); err != nil {
+			c.logger.Warn("failed to write bundle entry", "entry", name, "error", err)
+			return
+		}
+		written += int64(len(data))
+	}
+
+	if data, err := collectSystemInfo(); err != nil {
+		c.logger.Warn("failed to collect system info", "error", err)
+	} else {
+		addEntry("system.json", data)
+	}
+
+	addEntry("runtime.json", collectRuntimeInfo())
+	addEntry("goroutines.txt", collectGoroutineDump())
+
+	if data, err := collectHeapProfile(); err != nil {
+		c.logger.Warn("failed to collect heap profile", "error", err)
+	} else {
+		addEntry("heap.pprof", data)
+	}
+
+	if cpuProfile {
+		data, err := collectCPUProfile(ctx)
+		if err != nil {
+			c.logger.Warn("failed to collect CPU profile", "error", err)
+		} else {
+			addEntry("cpu.pprof", data)
+		}
+	}
+
+	if data, err := json.MarshalIndent(sources.CommandHistory, "", "  "); err != nil {
+		c.logger.Warn("failed to marshal command history", "error", err)
+	} else {
+		addEntry("command_history.json", redactSecrets(data))
+	}
+
+	if data, err := json.MarshalIndent(sources.Trading, "", "  "); err != nil {
+		c.logger.Warn("failed to marshal trading status", "error", err)
+	} else {
+		addEntry("trading.json", data)
+	}
+
+	addEntry("logs.txt", formatLogTail(sources.RecentLogs))
+
+	if len(dropped) > 0 {
+		c.logger.Warn("diagnostics bundle size cap reached, entries dropped", "max_size_mb", c.cfg.MaxSizeMB, "dropped", dropped)
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalize bundle: %w", err)
+	}
+
+	return path, nil
+}
+
+func collectSystemInfo() ([]byte, error) {
+	info, err := host.Info()
+	if err != nil {
+		return nil, err
+	}
+	vmem, err := mem.VirtualMemory()
+	if err != nil {
+		return nil, err
+	}
+	numCPU, err := cpu.Counts(true)
+	if err != nil {
+		numCPU = runtime.NumCPU()
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"hostname":        info.Hostname,
+		"os":              info.OS,
+		"platform":        info.Platform,
+		"kernel_version":  info.KernelVersion,
+		"arch":            info.KernelArch,
+		"num_cpu":         numCPU,
+		"memory_total_mb": vmem.Total / 1024 / 1024,
+		"uptime_seconds":  info.Uptime,
+	}, "", "  ")
+}
+
+func collectRuntimeInfo() []byte {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"go_version":    runtime.Version(),
+		"num_goroutine": runtime.NumGoroutine(),
+		"num_cpu":       runtime.NumCPU(),
+		"mem_stats":     memStats,
+	}, "", "  ")
+	if err != nil {
+		return []byte(fmt.Sprintf("failed to marshal runtime info: %v", err))
+	}
+	return data
+}
+
+func collectGoroutineDump() []byte {
+	var buf bytes.Buffer
+	_ = pprof.Lookup("goroutine").WriteTo(&buf, 2)
+	return buf.Bytes()
+}
+
+func collectHeapProfile() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("heap").WriteTo(&buf, 0); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func collectCPUProfile(ctx context.Context) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-time.After(cpuProfileDuration):
+	case <-ctx.Done():
+	}
+
+	pprof.StopCPUProfile()
+	return buf.Bytes(), nil
+}
+
+// redactSecretPattern matches common secret-bearing key/value pairs, JSON
+// or YAML style, case-insensitively. It exists because command_history.json
+// embeds spooled update_config commands verbatim, and those can carry a
+// plaintext api_key inline in cmd.Params["config"] (see internal/secrets).
+// Public-key fields such as command_signing_public_key are not matched.
+var redactSecretPattern = regexp.MustCompile(`(?i)("?(?:api_key|password|secret|private_key|signing_key)"?\s*[:=]\s*)("(?:[^"\\]|\\.)*"|'[^']*'|[^\s\\]+)`)
+
+// redactSecrets scrubs secret-shaped key/value pairs out of data before
+// it's embedded in a diagnostics bundle.
+func redactSecrets(data []byte) []byte {
+	return redactSecretPattern.ReplaceAll(data, []byte(`$1"[REDACTED]"`))
+}
+
+func formatLogTail(records []LogRecord) []byte {
+	var buf bytes.Buffer
+	for _, r := range records {
+		fmt.Fprintf(&buf, "%s %s\n", r.Time.Format(time.RFC3339), r.Line)
+	}
+	return buf.Bytes()
+}
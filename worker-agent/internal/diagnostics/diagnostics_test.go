@@ -0,0 +1,21 @@
+package diagnostics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactSecretsScrubsKnownKeys(t *testing.T) {
+	in := `{"command":{"params":{"config":"api_key: sk-live-abc123\nworker_id: w-1"}}}`
+	out := string(redactSecrets([]byte(in)))
+
+	if strings.Contains(out, "sk-live-abc123") {
+		t.Errorf("redactSecrets(%q) still contains the secret: %q", in, out)
+	}
+	if !strings.Contains(out, `api_key: "[REDACTED]"`) {
+		t.Errorf("redactSecrets(%q) = %q, want redacted api_key", in, out)
+	}
+	if !strings.Contains(out, "worker_id: w-1") {
+		t.Errorf("redactSecrets(%q) = %q, want non-secret fields preserved", in, out)
+	}
+}
@@ -2,28 +2,209 @@ package executor
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
-	"log"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/finetunelab/worker-agent/internal/diagnostics"
+	"github.com/finetunelab/worker-agent/internal/logging"
+	"github.com/finetunelab/worker-agent/internal/spool"
+	"github.com/finetunelab/worker-agent/internal/updater"
 	"github.com/finetunelab/worker-agent/pkg/api"
 )
 
+// restartExitDelay gives the caller a moment to flush a restart_agent
+// command's result back to the SaaS before the process exits or execs.
+const restartExitDelay = 2 * time.Second
+
+// defaultDrainTimeout bounds how long restart_agent waits for in-flight
+// commands to finish before restarting anyway.
+const defaultDrainTimeout = 30 * time.Second
+
+// execInPlaceFunc calls execInPlace; it's a package variable rather than a
+// direct call so tests can swap in a stub and observe executeRestartAgent's
+// drain/handoff behavior without actually replacing the test binary's
+// process image.
+var execInPlaceFunc = execInPlace
+
+// ConfigReloader validates and applies a raw update_config blob as a hot
+// config reload. It's implemented by internal/agent's ConfigManager;
+// Executor only depends on this narrow interface to avoid an import cycle
+// (agent already imports executor). diff describes what changed in a
+// human-readable form; validationErrors is non-empty only when the new
+// config failed validation (err is then nil), so executeUpdateConfig can
+// surface each one individually in CommandResult.Data.
+type ConfigReloader interface {
+	Reload(ctx context.Context, raw []byte, dryRun bool) (diff string, validationErrors []string, err error)
+}
+
 // Executor handles command execution
 type Executor struct {
-	tradingService *TradingService
+	Logger hclog.Logger
+
+	tradingService   *TradingService
+	registry         *Registry
+	signingKey       atomic.Pointer[ed25519.PublicKey]
+	logRingBuffer    *logging.RingBuffer
+	updater          *updater.Updater
+	spooler          *spool.Spooler
+	diag             *diagnostics.Collector
+	configReloader   ConfigReloader
+	restartStatePath string
+	drainTimeout     time.Duration
+
+	// baseCtx is the long-lived context trading runs under. It must not be
+	// the per-command context Execute derives (that's canceled the instant
+	// the start_trading command returns, which would tear down every
+	// strategy goroutine immediately).
+	baseCtx context.Context
+
+	inFlight sync.WaitGroup
+	draining atomic.Bool
 }
 
-// New creates a new executor
-func New() *Executor {
-	return &Executor{
-		tradingService: NewTradingService(),
+// New creates a new executor. signingKey, if non-empty, is the Ed25519
+// public key used to verify Command.Signature; commands are rejected
+// unless they carry a valid signature. An empty key disables verification,
+// which is only appropriate for local development. logRingBuffer, if
+// non-nil, is surfaced as a recent log tail by collect_diagnostics. upd, if
+// non-nil, is used by restart_agent to check for and apply a newer agent
+// release before restarting; a nil upd falls back to acknowledging the
+// restart without self-updating. spooler, if non-nil, receives every
+// executed command's result and backs the purge command; its records also
+// feed collect_diagnostics's command_history.json. diag, if non-nil, is
+// used by collect_diagnostics to assemble a diagnostics bundle; a nil diag
+// falls back to the old trivial summary. configReloader, if non-nil, backs
+// update_config with a full hot-reload pipeline (validation, diff, dry-run,
+// atomic swap); a nil configReloader falls back to acknowledging the
+// command without applying anything. restartStatePath is where
+// restart_agent writes its handoff file for the replacement process to pick
+// up; drainTimeout bounds how long restart_agent waits for in-flight
+// commands before restarting anyway (<= 0 uses defaultDrainTimeout). ctx is
+// the agent's long-lived context: it outlives any single command and is
+// what trading actually runs under, since the per-command context passed
+// to Execute is canceled as soon as that command's handler returns. A nil
+// ctx falls back to context.Background().
+// logger, if nil, discards all log output; callers that want operators to
+// be able to bump verbosity via set_log_level should pass a logger whose
+// level is shared with the rest of the agent (e.g. via logger.Named, not a
+// fresh hclog.New).
+func New(ctx context.Context, signingKey ed25519.PublicKey, logRingBuffer *logging.RingBuffer, upd *updater.Updater, spooler *spool.Spooler, diag *diagnostics.Collector, configReloader ConfigReloader, restartStatePath string, drainTimeout time.Duration, logger hclog.Logger) *Executor {
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+	if ctx == nil {
+		ctx = context.Background()
 	}
+
+	e := &Executor{
+		Logger:           logger,
+		tradingService:   NewTradingService(logger.Named("trading")),
+		registry:         NewRegistry(),
+		logRingBuffer:    logRingBuffer,
+		updater:          upd,
+		spooler:          spooler,
+		diag:             diag,
+		configReloader:   configReloader,
+		restartStatePath: restartStatePath,
+		drainTimeout:     drainTimeout,
+		baseCtx:          ctx,
+	}
+	e.signingKey.Store(&signingKey)
+	e.registerBuiltins()
+
+	if e.spooler != nil {
+		if records, err := e.spooler.List(); err != nil {
+			e.Logger.Error("failed to scan command spool", "error", err)
+		} else {
+			e.Logger.Info("found spooled commands from previous runs", "count", len(records))
+		}
+	}
+
+	if state, resumed := readRestartState(e.restartStatePath, e.Logger); resumed {
+		e.Logger.Info("resumed after restart", "last_command_id", state.LastCommandID, "spool_cursor", state.SpoolCursor, "previous_pid", state.PID)
+	}
+
+	return e
+}
+
+// registerBuiltins wires up the command types the agent ships with. Hosts
+// embedding the agent can add more via RegisterHandler.
+func (e *Executor) registerBuiltins() {
+	e.registry.Register(api.CommandStartTrading, e.executeStartTrading)
+	e.registry.Register(api.CommandStopTrading, e.executeStopTrading)
+	e.registry.Register(api.CommandUpdateConfig, e.executeUpdateConfig)
+	e.registry.Register(api.CommandRestartAgent, e.executeRestartAgent)
+	e.registry.Register(api.CommandCollectDiag, e.executeCollectDiagnostics)
+	e.registry.Register(api.CommandRunScript, e.executeRunScript)
+	e.registry.Register(api.CommandPurge, e.executePurge)
+	e.registry.Register(api.CommandSetLogLevel, e.executeSetLogLevel)
+}
+
+// Trading returns the executor's trading service, e.g. so a config hot
+// reload can start/stop it to match a changed trading_enabled/trading_config.
+func (e *Executor) Trading() *TradingService {
+	return e.tradingService
+}
+
+// RegisterHandler adds or replaces the handler for a command type.
+func (e *Executor) RegisterHandler(cmdType api.CommandType, h Handler) {
+	e.registry.Register(cmdType, h)
+}
+
+// SetSigningKey atomically swaps the Ed25519 public key used to verify
+// Command.Signature, so a config hot reload of command_signing_public_key
+// takes effect on the next Execute instead of only at the next restart. A
+// nil key disables verification.
+func (e *Executor) SetSigningKey(key ed25519.PublicKey) {
+	e.signingKey.Store(&key)
 }
 
 // Execute executes a command and returns the result
 func (e *Executor) Execute(ctx context.Context, cmd api.Command) api.CommandResult {
-	log.Printf("[Executor] Executing command: %s (ID: %s)", cmd.CommandType, cmd.ID)
+	cmdLogger := e.Logger.Named("command").With(
+		"command_id", cmd.ID,
+		"command_type", string(cmd.CommandType),
+		"trace_id", newTraceID(),
+	)
+	ctx = withLogger(ctx, cmdLogger)
+
+	cmdLogger.Info("executing command")
+
+	if e.draining.Load() && cmd.CommandType != api.CommandRestartAgent {
+		cmdLogger.Warn("rejecting command: agent is draining for restart")
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     "agent is draining for restart, not accepting new commands",
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	if err := verifySignature(cmd, *e.signingKey.Load()); err != nil {
+		cmdLogger.Warn("rejecting command", "error", err)
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     fmt.Sprintf("signature verification failed: %v", err),
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	// restart_agent waits on e.inFlight itself, so it must not be tracked in
+	// it, or it would wait on its own completion forever.
+	if cmd.CommandType != api.CommandRestartAgent {
+		e.inFlight.Add(1)
+		defer e.inFlight.Done()
+	}
 
 	// Set timeout from command
 	timeout := time.Duration(cmd.TimeoutSeconds) * time.Second
@@ -35,18 +216,9 @@ func (e *Executor) Execute(ctx context.Context, cmd api.Command) api.CommandResu
 
 	// Execute based on command type
 	var result api.CommandResult
-	switch cmd.CommandType {
-	case api.CommandStartTrading:
-		result = e.executeStartTrading(ctx, cmd)
-	case api.CommandStopTrading:
-		result = e.executeStopTrading(ctx, cmd)
-	case api.CommandUpdateConfig:
-		result = e.executeUpdateConfig(ctx, cmd)
-	case api.CommandRestartAgent:
-		result = e.executeRestartAgent(ctx, cmd)
-	case api.CommandCollectDiag:
-		result = e.executeCollectDiagnostics(ctx, cmd)
-	default:
+	if handler, ok := e.registry.Lookup(cmd.CommandType); ok {
+		result = handler(ctx, cmd)
+	} else {
 		result = api.CommandResult{
 			CommandID: cmd.ID,
 			Status:    "error",
@@ -62,20 +234,33 @@ func (e *Executor) Execute(ctx context.Context, cmd api.Command) api.CommandResu
 	}
 	result.Data["execution_time_ms"] = executionTime.Milliseconds()
 
-	log.Printf("[Executor] Command %s completed: %s (duration: %v)", cmd.ID, result.Status, executionTime)
+	if e.spooler != nil {
+		if err := e.spooler.Write(cmd, result); err != nil {
+			cmdLogger.Error("failed to spool command result", "error", err)
+		}
+	}
+
+	if result.Status == "error" || result.Status == "failed" {
+		cmdLogger.Error("command completed", "status", result.Status, "duration", executionTime, "error", result.Error)
+	} else {
+		cmdLogger.Info("command completed", "status", result.Status, "duration", executionTime)
+	}
 
 	return result
 }
 
-// executeStartTrading starts the trading service
+// executeStartTrading starts the trading service. It deliberately runs
+// trading under e.baseCtx rather than the command's ctx: ctx is canceled
+// the moment this handler returns (see Execute), which would tear down
+// every strategy goroutine before trading ever processed a tick.
 func (e *Executor) executeStartTrading(ctx context.Context, cmd api.Command) api.CommandResult {
-	log.Println("[Executor] Starting trading...")
+	loggerFromContext(ctx).Info("starting trading")
 
 	// Extract params
 	configPath, _ := cmd.Params["config"].(string)
 
 	// Start trading
-	if err := e.tradingService.Start(ctx, configPath); err != nil {
+	if err := e.tradingService.Start(e.baseCtx, configPath); err != nil {
 		return api.CommandResult{
 			CommandID: cmd.ID,
 			Status:    "failed",
@@ -98,7 +283,7 @@ func (e *Executor) executeStartTrading(ctx context.Context, cmd api.Command) api
 
 // executeStopTrading stops the trading service
 func (e *Executor) executeStopTrading(ctx context.Context, cmd api.Command) api.CommandResult {
-	log.Println("[Executor] Stopping trading...")
+	loggerFromContext(ctx).Info("stopping trading")
 
 	// Stop trading
 	if err := e.tradingService.Stop(ctx); err != nil {
@@ -121,54 +306,344 @@ func (e *Executor) executeStopTrading(ctx context.Context, cmd api.Command) api.
 	}
 }
 
-// executeUpdateConfig updates the agent configuration
+// executeUpdateConfig hot-reloads the agent configuration via e.configReloader:
+// a new config YAML/JSON blob, given either inline as cmd.Params["config"] or
+// as a file path in cmd.Params["path"], is validated and diffed against the
+// live config before being applied. cmd.Params["dry_run"] == true returns the
+// diff without applying anything.
 func (e *Executor) executeUpdateConfig(ctx context.Context, cmd api.Command) api.CommandResult {
-	log.Println("[Executor] Updating config...")
+	logger := loggerFromContext(ctx)
+
+	if e.configReloader == nil {
+		logger.Warn("update_config received but no config reloader is configured")
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "completed",
+			Output:    "Config update acknowledged (not yet implemented)",
+			Data: map[string]interface{}{
+				"params": cmd.Params,
+			},
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	dryRun, _ := cmd.Params["dry_run"].(bool)
+
+	var raw []byte
+	if inline, ok := cmd.Params["config"].(string); ok && inline != "" {
+		raw = []byte(inline)
+	} else if path, ok := cmd.Params["path"].(string); ok && path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return api.CommandResult{
+				CommandID: cmd.ID,
+				Status:    "error",
+				Error:     fmt.Sprintf("failed to read config file: %v", err),
+				Timestamp: time.Now().Unix(),
+			}
+		}
+		raw = data
+	} else {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     "update_config requires a \"config\" or \"path\" param",
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	logger.Info("reloading config", "dry_run", dryRun)
+
+	diff, validationErrors, err := e.configReloader.Reload(ctx, raw, dryRun)
+	if len(validationErrors) > 0 {
+		logger.Warn("config reload rejected by validation", "errors", validationErrors)
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     "config failed validation",
+			Data: map[string]interface{}{
+				"validation_errors": validationErrors,
+			},
+			Timestamp: time.Now().Unix(),
+		}
+	}
+	if err != nil {
+		logger.Error("config reload failed", "error", err)
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     fmt.Sprintf("failed to reload config: %v", err),
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	output := "Config reloaded"
+	if dryRun {
+		output = "Config reload dry-run: no changes applied"
+	}
 
-	// TODO: Implement config update logic
-	// For now, just acknowledge
 	return api.CommandResult{
 		CommandID: cmd.ID,
 		Status:    "completed",
-		Output:    "Config update acknowledged (not yet implemented)",
+		Output:    output,
 		Data: map[string]interface{}{
-			"params": cmd.Params,
+			"dry_run": dryRun,
+			"diff":    diff,
 		},
 		Timestamp: time.Now().Unix(),
 	}
 }
 
-// executeRestartAgent restarts the agent
+// executeRestartAgent checks for and applies a newer agent release, drains
+// in-flight commands, stops the trading service, leaves a handoff file for
+// the replacement process, then restarts. On Windows the restart is a
+// detached child process plus os.Exit; on every other platform it's an
+// in-place syscall.Exec (same PID) (see the execInPlace implementations).
 func (e *Executor) executeRestartAgent(ctx context.Context, cmd api.Command) api.CommandResult {
-	log.Println("[Executor] Restart agent requested...")
+	logger := loggerFromContext(ctx)
+	logger.Info("restart agent requested")
+
+	output := "Restarting agent"
+
+	if e.updater != nil {
+		manifest, available, err := e.updater.CheckForUpdate(ctx)
+		if err != nil {
+			return api.CommandResult{
+				CommandID: cmd.ID,
+				Status:    "error",
+				Error:     fmt.Sprintf("failed to check for update: %v", err),
+				Timestamp: time.Now().Unix(),
+			}
+		}
+
+		if available {
+			if err := e.updater.Apply(ctx, manifest); err != nil {
+				return api.CommandResult{
+					CommandID: cmd.ID,
+					Status:    "error",
+					Error:     fmt.Sprintf("failed to apply update: %v", err),
+					Timestamp: time.Now().Unix(),
+				}
+			}
+			output = fmt.Sprintf("Updated to %s, restarting", manifest.Version)
+		} else {
+			output = "Already on latest version, restarting"
+		}
+	}
+
+	e.draining.Store(true)
+
+	if err := e.tradingService.Stop(ctx); err != nil {
+		logger.Warn("trading service stop during restart", "error", err)
+	}
+
+	drained := true
+	done := make(chan struct{})
+	go func() {
+		e.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(e.drainTimeout):
+		drained = false
+		logger.Warn("in-flight commands did not drain in time, restarting anyway", "drain_timeout", e.drainTimeout)
+	}
+
+	var spoolCursor string
+	if e.spooler != nil {
+		if cursor, err := e.spooler.Cursor(); err != nil {
+			logger.Error("failed to read spool cursor for restart handoff", "error", err)
+		} else {
+			spoolCursor = cursor
+		}
+	}
+
+	pid := os.Getpid()
+	if e.restartStatePath != "" {
+		state := restartState{
+			LastCommandID: cmd.ID,
+			SpoolCursor:   spoolCursor,
+			PID:           pid,
+			Time:          time.Now(),
+		}
+		if err := writeRestartState(e.restartStatePath, state); err != nil {
+			logger.Error("failed to write restart handoff file", "error", err)
+		}
+	}
+
+	go func() {
+		time.Sleep(restartExitDelay)
+		if err := execInPlaceFunc(); err != nil {
+			logger.Error("exec re-spawn failed, falling back to plain exit", "error", err)
+			os.Exit(0)
+		}
+	}()
 
-	// TODO: Implement graceful restart
-	// For now, just acknowledge
 	return api.CommandResult{
 		CommandID: cmd.ID,
 		Status:    "completed",
-		Output:    "Agent restart acknowledged (requires manual restart for now)",
+		Output:    output,
+		Data: map[string]interface{}{
+			"pid":     pid,
+			"drained": drained,
+		},
 		Timestamp: time.Now().Unix(),
 	}
 }
 
-// executeCollectDiagnostics collects diagnostic information
+// executeCollectDiagnostics assembles a diagnostics bundle (system info,
+// Go runtime stats, a goroutine dump, an optional CPU profile, command
+// history, and trading status) and writes it as a gzipped tar file, whose
+// path is returned in Data.bundle_path. Set cmd.Params["cpu_profile"] to
+// true to additionally sample a 30s CPU profile. Falls back to a trivial
+// summary if no diagnostics collector is configured.
 func (e *Executor) executeCollectDiagnostics(ctx context.Context, cmd api.Command) api.CommandResult {
-	log.Println("[Executor] Collecting diagnostics...")
+	logger := loggerFromContext(ctx)
+	logger.Info("collecting diagnostics")
+
+	tradingInfo := map[string]interface{}{
+		"status":      e.tradingService.GetStatus(),
+		"uptime":      e.tradingService.GetUptime(),
+		"config_path": e.tradingService.ConfigPath(),
+		"orders":      e.tradingService.RecentOrders(),
+		"fills":       e.tradingService.RecentFills(),
+	}
+
+	if e.diag == nil {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "completed",
+			Output:    "Diagnostics collected",
+			Data: map[string]interface{}{
+				"trading":         tradingInfo,
+				"command_history": e.commandHistory(),
+				"recent_logs":     e.recentLogs(),
+			},
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	cpuProfile, _ := cmd.Params["cpu_profile"].(bool)
 
-	// Collect diagnostics
-	diagnostics := map[string]interface{}{
-		"trading_status":  e.tradingService.GetStatus(),
-		"trading_uptime":  e.tradingService.GetUptime(),
-		"command_history": "not_implemented",
-		"system_info":     "not_implemented",
+	bundlePath, err := e.diag.Collect(ctx, diagnostics.Sources{
+		CommandHistory: e.commandHistory(),
+		Trading:        tradingInfo,
+		RecentLogs:     e.diagLogRecords(),
+	}, cpuProfile)
+	if err != nil {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     fmt.Sprintf("failed to collect diagnostics bundle: %v", err),
+			Timestamp: time.Now().Unix(),
+		}
 	}
 
 	return api.CommandResult{
 		CommandID: cmd.ID,
 		Status:    "completed",
-		Output:    "Diagnostics collected",
-		Data:      diagnostics,
+		Output:    "Diagnostics bundle collected",
+		Data: map[string]interface{}{
+			"bundle_path": bundlePath,
+		},
+		Timestamp: time.Now().Unix(),
+	}
+}
+
+// recentLogs returns the current log tail, if a ring buffer is configured.
+func (e *Executor) recentLogs() []logging.Record {
+	if e.logRingBuffer == nil {
+		return nil
+	}
+	return e.logRingBuffer.Snapshot()
+}
+
+// diagLogRecords adapts recentLogs to the diagnostics package's LogRecord
+// type, keeping diagnostics decoupled from the logging ring buffer.
+func (e *Executor) diagLogRecords() []diagnostics.LogRecord {
+	records := e.recentLogs()
+	out := make([]diagnostics.LogRecord, len(records))
+	for i, r := range records {
+		out[i] = diagnostics.LogRecord{Time: r.Time, Line: r.Line}
+	}
+	return out
+}
+
+// commandHistory returns the spooled command records, if a spooler is
+// configured.
+func (e *Executor) commandHistory() interface{} {
+	if e.spooler == nil {
+		return "not_implemented"
+	}
+	records, err := e.spooler.List()
+	if err != nil {
+		return fmt.Sprintf("failed to read command spool: %v", err)
+	}
+	return records
+}
+
+// executePurge forces an out-of-band sweep of the command spool and
+// reports what it found.
+func (e *Executor) executePurge(ctx context.Context, cmd api.Command) api.CommandResult {
+	loggerFromContext(ctx).Info("purging command spool")
+
+	if e.spooler == nil {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     "command spool is not configured",
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	report, err := e.spooler.Sweep()
+	if err != nil {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     fmt.Sprintf("failed to sweep command spool: %v", err),
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	return api.CommandResult{
+		CommandID: cmd.ID,
+		Status:    "completed",
+		Output:    "Command spool purged",
+		Data: map[string]interface{}{
+			"purged": report.Purged,
+			"age":    report.Age.String(),
+			"size":   report.Size,
+			"files":  report.Files,
+		},
+		Timestamp: time.Now().Unix(),
+	}
+}
+
+// executeSetLogLevel changes the agent's log verbosity at runtime. Since
+// every component logger is derived from e.Logger via Named/With, they all
+// share its underlying level, so this takes effect immediately across the
+// agent without a restart.
+func (e *Executor) executeSetLogLevel(ctx context.Context, cmd api.Command) api.CommandResult {
+	levelStr, _ := cmd.Params["level"].(string)
+	level := hclog.LevelFromString(levelStr)
+	if level == hclog.NoLevel {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     fmt.Sprintf("invalid log level: %q", levelStr),
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	loggerFromContext(ctx).Info("changing log level", "new_level", level.String())
+	e.Logger.SetLevel(level)
+
+	return api.CommandResult{
+		CommandID: cmd.ID,
+		Status:    "completed",
+		Output:    fmt.Sprintf("Log level set to %s", level.String()),
 		Timestamp: time.Now().Unix(),
 	}
 }
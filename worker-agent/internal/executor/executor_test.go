@@ -0,0 +1,91 @@
+package executor
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+// testBlockCommand is a command type registered by these tests whose
+// handler blocks until a test-controlled channel is closed, simulating an
+// in-flight command executeRestartAgent has to wait out.
+const testBlockCommand api.CommandType = "test_block"
+
+// TestMain stubs out execInPlaceFunc for every test in this package, so
+// exercising executeRestartAgent never actually execs over the test
+// binary's own process image.
+func TestMain(m *testing.M) {
+	execInPlaceFunc = func() error { return nil }
+	os.Exit(m.Run())
+}
+
+func TestExecuteRestartAgentWaitsForInFlightDrain(t *testing.T) {
+	e := New(context.Background(), nil, nil, nil, nil, nil, nil, "", time.Second, nil)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	e.RegisterHandler(testBlockCommand, func(ctx context.Context, cmd api.Command) api.CommandResult {
+		close(started)
+		<-unblock
+		return api.CommandResult{CommandID: cmd.ID, Status: "completed"}
+	})
+
+	blockDone := make(chan api.CommandResult, 1)
+	go func() {
+		blockDone <- e.Execute(context.Background(), api.Command{ID: "block-1", CommandType: testBlockCommand, TimeoutSeconds: 5})
+	}()
+	<-started
+
+	restartDone := make(chan api.CommandResult, 1)
+	go func() {
+		restartDone <- e.executeRestartAgent(context.Background(), api.Command{ID: "restart-1"})
+	}()
+
+	select {
+	case <-restartDone:
+		t.Fatal("executeRestartAgent returned before the in-flight command finished, want it to wait for drain")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(unblock)
+
+	select {
+	case result := <-restartDone:
+		if result.Data["drained"] != true {
+			t.Errorf("restart result Data[\"drained\"] = %v, want true", result.Data["drained"])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("executeRestartAgent did not return after the in-flight command finished")
+	}
+
+	<-blockDone
+}
+
+func TestExecuteRestartAgentRestartsAfterDrainTimeout(t *testing.T) {
+	const drainTimeout = 50 * time.Millisecond
+	e := New(context.Background(), nil, nil, nil, nil, nil, nil, "", drainTimeout, nil)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	defer close(unblock)
+	e.RegisterHandler(testBlockCommand, func(ctx context.Context, cmd api.Command) api.CommandResult {
+		close(started)
+		<-unblock
+		return api.CommandResult{CommandID: cmd.ID, Status: "completed"}
+	})
+
+	go e.Execute(context.Background(), api.Command{ID: "block-1", CommandType: testBlockCommand, TimeoutSeconds: 5})
+	<-started
+
+	start := time.Now()
+	result := e.executeRestartAgent(context.Background(), api.Command{ID: "restart-1"})
+	if elapsed := time.Since(start); elapsed < drainTimeout {
+		t.Errorf("executeRestartAgent returned after %v, want at least drainTimeout %v", elapsed, drainTimeout)
+	}
+	if result.Data["drained"] != false {
+		t.Errorf("restart result Data[\"drained\"] = %v, want false (drain timed out)", result.Data["drained"])
+	}
+}
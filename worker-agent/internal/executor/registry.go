@@ -0,0 +1,39 @@
+package executor
+
+import (
+	"context"
+	"sync"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+// Handler executes a single command type and returns its result.
+type Handler func(ctx context.Context, cmd api.Command) api.CommandResult
+
+// Registry maps command types to the handler that executes them, so new
+// command types can be added without growing a single switch statement.
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[api.CommandType]Handler
+}
+
+// NewRegistry creates an empty command registry.
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[api.CommandType]Handler)}
+}
+
+// Register associates a handler with a command type, replacing any
+// previously registered handler for that type.
+func (r *Registry) Register(cmdType api.CommandType, h Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[cmdType] = h
+}
+
+// Lookup returns the handler registered for cmdType, if any.
+func (r *Registry) Lookup(cmdType api.CommandType) (Handler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.handlers[cmdType]
+	return h, ok
+}
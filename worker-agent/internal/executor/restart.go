@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// restartState is the handoff a process about to exec() itself leaves for
+// its replacement to pick up on startup.
+type restartState struct {
+	LastCommandID string    `json:"last_command_id"`
+	SpoolCursor   string    `json:"spool_cursor"`
+	PID           int       `json:"pid"`
+	Time          time.Time `json:"time"`
+}
+
+// writeRestartState persists state to path.
+func writeRestartState(path string, state restartState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal restart state: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// readRestartState reads and removes the handoff file at path, if any. A
+// missing file is not an error; it just means this isn't a resume.
+func readRestartState(path string, logger hclog.Logger) (*restartState, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	defer os.Remove(path)
+
+	var state restartState
+	if err := json.Unmarshal(data, &state); err != nil {
+		logger.Error("failed to parse restart handoff file", "path", path, "error", err)
+		return nil, false
+	}
+
+	return &state, true
+}
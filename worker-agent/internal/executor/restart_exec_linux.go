@@ -0,0 +1,28 @@
+//go:build linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// execInPlace replaces the current process image with a fresh copy of the
+// running binary, keeping the same PID and re-using the original argv/env.
+// It resolves the binary path via os.Executable() rather than
+// /proc/self/exe: when updater.Apply has just renamed a new binary over
+// BinaryPath, /proc/self/exe still points at the old, now-unlinked inode,
+// so execing it would relaunch the stale pre-update binary instead of
+// picking up the update.
+func execInPlace() error {
+	binaryPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to resolve running binary path: %w", err)
+	}
+
+	if err := syscall.Exec(binaryPath, os.Args, os.Environ()); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
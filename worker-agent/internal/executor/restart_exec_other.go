@@ -0,0 +1,25 @@
+//go:build !linux && !windows
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// execInPlace replaces the current process image with a fresh copy of the
+// running binary, keeping the same PID and re-using the original argv/env.
+// As in restart_exec_linux.go, the binary path is resolved via
+// os.Executable().
+func execInPlace() error {
+	binaryPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to resolve running binary path: %w", err)
+	}
+
+	if err := syscall.Exec(binaryPath, os.Args, os.Environ()); err != nil {
+		return fmt.Errorf("exec failed: %w", err)
+	}
+	return nil
+}
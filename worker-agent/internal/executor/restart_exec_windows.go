@@ -0,0 +1,33 @@
+//go:build windows
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// execInPlace has no true process-image-replacement equivalent on Windows
+// (syscall.Exec doesn't exist there), so it spawns a detached copy of the
+// running binary with the original argv/env and exits this process. Unlike
+// the Linux/other implementations, the PID changes across this restart.
+func execInPlace() error {
+	binaryPath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to resolve running binary path: %w", err)
+	}
+
+	cmd := exec.Command(binaryPath, os.Args[1:]...)
+	cmd.Env = os.Environ()
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to spawn replacement process: %w", err)
+	}
+
+	os.Exit(0)
+	return nil
+}
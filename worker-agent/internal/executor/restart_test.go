@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func TestWriteReadRestartStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "restart-state.json")
+
+	want := restartState{
+		LastCommandID: "cmd-123",
+		SpoolCursor:   "cursor-abc",
+		PID:           4242,
+		Time:          time.Now().Truncate(time.Second),
+	}
+
+	if err := writeRestartState(path, want); err != nil {
+		t.Fatalf("writeRestartState: %v", err)
+	}
+
+	got, resumed := readRestartState(path, hclog.NewNullLogger())
+	if !resumed {
+		t.Fatal("readRestartState: resumed = false, want true")
+	}
+	if got.LastCommandID != want.LastCommandID || got.SpoolCursor != want.SpoolCursor || got.PID != want.PID || !got.Time.Equal(want.Time) {
+		t.Errorf("readRestartState = %+v, want %+v", *got, want)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("handoff file still exists at %q after readRestartState, want it removed", path)
+	}
+}
+
+func TestReadRestartStateNoFileIsNotResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "restart-state.json")
+
+	state, resumed := readRestartState(path, hclog.NewNullLogger())
+	if resumed {
+		t.Errorf("readRestartState with no file: resumed = true, want false")
+	}
+	if state != nil {
+		t.Errorf("readRestartState with no file: state = %+v, want nil", state)
+	}
+}
@@ -0,0 +1,22 @@
+package executor
+
+// ResourceLimits caps the resources a sandboxed script may consume.
+type ResourceLimits struct {
+	CPUPercent int   // percentage of one CPU core, e.g. 50 for half a core
+	MemoryMB   int64 // resident memory limit in MB
+}
+
+// sandbox enforces ResourceLimits on a started process and releases any
+// held resources (e.g. a cgroup) once the process has exited.
+type sandbox interface {
+	// AttachProcess applies the sandbox's limits to the already-started
+	// process with the given PID.
+	AttachProcess(pid int) error
+	// Close releases any resources the sandbox holds.
+	Close() error
+}
+
+// newSandbox prepares cmd to run under the given limits on the current
+// platform and returns a handle used to attach the limits once the
+// process has started. Implemented per-platform in sandbox_linux.go,
+// sandbox_windows.go, and sandbox_other.go.
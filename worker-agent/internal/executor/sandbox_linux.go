@@ -0,0 +1,69 @@
+//go:build linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"syscall"
+)
+
+// safeIDPattern restricts the command ID used to build the cgroup path to
+// characters that can't contain path separators or traversal segments.
+// cmd.ID arrives over the network (see pkg/api.Command) and signature
+// verification of it is optional, so it must not be trusted as a path
+// component as-is.
+var safeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// cgroupLinuxSandbox enforces ResourceLimits via a cgroup v2 subtree. If
+// the host doesn't have a writable cgroup v2 hierarchy (e.g. running
+// unprivileged, or cgroup v1 only), newSandbox returns an error and the
+// caller falls back to running the script without resource limits.
+type cgroupLinuxSandbox struct {
+	path string
+}
+
+func newSandbox(cmd *exec.Cmd, id string, limits ResourceLimits) (sandbox, error) {
+	if !safeIDPattern.MatchString(id) {
+		return nil, fmt.Errorf("invalid command id %q for cgroup path", id)
+	}
+
+	cgroupPath := filepath.Join("/sys/fs/cgroup", "worker-agent", id)
+	if err := os.MkdirAll(cgroupPath, 0755); err != nil {
+		return nil, fmt.Errorf("cgroups unavailable: %w", err)
+	}
+
+	if limits.CPUPercent > 0 {
+		quota := limits.CPUPercent * 1000 // microseconds out of a 100ms period
+		if err := os.WriteFile(filepath.Join(cgroupPath, "cpu.max"), []byte(fmt.Sprintf("%d 100000", quota)), 0644); err != nil {
+			os.Remove(cgroupPath)
+			return nil, fmt.Errorf("failed to set cpu.max: %w", err)
+		}
+	}
+
+	if limits.MemoryMB > 0 {
+		bytes := limits.MemoryMB * 1024 * 1024
+		if err := os.WriteFile(filepath.Join(cgroupPath, "memory.max"), []byte(strconv.FormatInt(bytes, 10)), 0644); err != nil {
+			os.Remove(cgroupPath)
+			return nil, fmt.Errorf("failed to set memory.max: %w", err)
+		}
+	}
+
+	// New process group so the whole tree (including any children the
+	// script spawns) can be torn down together if needed.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	return &cgroupLinuxSandbox{path: cgroupPath}, nil
+}
+
+func (s *cgroupLinuxSandbox) AttachProcess(pid int) error {
+	return os.WriteFile(filepath.Join(s.path, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644)
+}
+
+func (s *cgroupLinuxSandbox) Close() error {
+	return os.Remove(s.path)
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package executor
+
+import "testing"
+
+func TestNewSandboxRejectsPathTraversalID(t *testing.T) {
+	ids := []string{"../../etc/passwd", "..", "foo/bar", "foo/../bar", ""}
+	for _, id := range ids {
+		if _, err := newSandbox(nil, id, ResourceLimits{}); err == nil {
+			t.Errorf("newSandbox(id=%q) = nil error, want rejection of path-traversal ID", id)
+		}
+	}
+}
+
+func TestSafeIDPatternAcceptsOrdinaryCommandIDs(t *testing.T) {
+	ids := []string{"cmd-1", "cmd_1", "AbC123"}
+	for _, id := range ids {
+		if !safeIDPattern.MatchString(id) {
+			t.Errorf("safeIDPattern.MatchString(%q) = false, want true", id)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// newSandbox has no resource-limiting backend outside Linux (cgroups) and
+// Windows (job objects); the script still runs under the command timeout
+// and a minimal environment, just without CPU/memory caps.
+func newSandbox(cmd *exec.Cmd, id string, limits ResourceLimits) (sandbox, error) {
+	return nil, fmt.Errorf("resource-limited sandboxing is not supported on this platform")
+}
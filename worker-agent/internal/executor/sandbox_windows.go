@@ -0,0 +1,90 @@
+//go:build windows
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// jobObjectSandbox enforces ResourceLimits using a Windows job object, so
+// the limits apply to the script process and any children it spawns.
+type jobObjectSandbox struct {
+	handle windows.Handle
+}
+
+// jobObjectExtendedLimitInformation mirrors
+// JOBOBJECT_EXTENDED_LIMIT_INFORMATION for the fields we set.
+type jobObjectExtendedLimitInformation struct {
+	BasicLimitInformation struct {
+		PerProcessUserTimeLimit int64
+		PerJobUserTimeLimit     int64
+		LimitFlags              uint32
+		MinimumWorkingSetSize   uintptr
+		MaximumWorkingSetSize   uintptr
+		ActiveProcessLimit      uint32
+		Affinity                uintptr
+		PriorityClass           uint32
+		SchedulingClass         uint32
+	}
+	IoInfo                    [48]byte // unused; padding to match IO_COUNTERS
+	ProcessMemoryLimit        uintptr
+	JobMemoryLimit            uintptr
+	PeakProcessMemoryUsed     uintptr
+	PeakJobMemoryUsed         uintptr
+}
+
+const (
+	jobObjectExtendedLimitInformationClass = 9
+	jobObjectLimitProcessMemory            = 0x00000100
+	jobObjectLimitJobMemory                = 0x00000200
+)
+
+func newSandbox(cmd *exec.Cmd, id string, limits ResourceLimits) (sandbox, error) {
+	handle, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job object: %w", err)
+	}
+
+	if limits.MemoryMB > 0 {
+		var info jobObjectExtendedLimitInformation
+		limitBytes := uintptr(limits.MemoryMB) * 1024 * 1024
+		info.ProcessMemoryLimit = limitBytes
+		info.JobMemoryLimit = limitBytes
+		info.BasicLimitInformation.LimitFlags = jobObjectLimitProcessMemory | jobObjectLimitJobMemory
+
+		_, err := windows.SetInformationJobObject(
+			handle,
+			jobObjectExtendedLimitInformationClass,
+			uintptr(unsafe.Pointer(&info)),
+			uint32(unsafe.Sizeof(info)),
+		)
+		if err != nil {
+			windows.CloseHandle(handle)
+			return nil, fmt.Errorf("failed to set job object memory limit: %w", err)
+		}
+	}
+
+	// CPUPercent isn't enforced via the job object here (it requires the
+	// newer JOBOBJECT_CPU_RATE_CONTROL_INFORMATION class); the memory cap
+	// and the command-level timeout are the primary guardrails on Windows.
+
+	return &jobObjectSandbox{handle: handle}, nil
+}
+
+func (s *jobObjectSandbox) AttachProcess(pid int) error {
+	h, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		return fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer windows.CloseHandle(h)
+
+	return windows.AssignProcessToJobObject(s.handle, h)
+}
+
+func (s *jobObjectSandbox) Close() error {
+	return windows.CloseHandle(s.handle)
+}
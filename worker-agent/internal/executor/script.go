@@ -0,0 +1,221 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+// maxCapturedOutputBytes bounds how much stdout/stderr we buffer per
+// stream; beyond this the output is truncated rather than growing
+// unbounded for runaway scripts.
+const maxCapturedOutputBytes = 1 << 20 // 1 MiB
+
+// defaultScriptCPUPercent and defaultScriptMemoryMB are the resource caps
+// applied when a run_script command doesn't specify its own.
+const (
+	defaultScriptCPUPercent = 50
+	defaultScriptMemoryMB   = 512
+)
+
+// executeRunScript runs an operator-supplied script inside a sandboxed
+// working directory with resource limits and a hard timeout (enforced by
+// the ctx deadline set in Execute from cmd.TimeoutSeconds).
+func (e *Executor) executeRunScript(ctx context.Context, cmd api.Command) api.CommandResult {
+	logger := loggerFromContext(ctx)
+	logger.Info("running sandboxed script")
+
+	script, _ := cmd.Params["script"].(string)
+	if script == "" {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     "run_script requires a non-empty \"script\" param",
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	interpreter, _ := cmd.Params["interpreter"].(string)
+	if interpreter == "" {
+		interpreter = defaultInterpreter()
+	}
+
+	sandboxDir, err := os.MkdirTemp("", "worker-agent-script-*")
+	if err != nil {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     fmt.Sprintf("failed to create sandbox dir: %v", err),
+			Timestamp: time.Now().Unix(),
+		}
+	}
+	defer os.RemoveAll(sandboxDir)
+
+	scriptPath := filepath.Join(sandboxDir, scriptFileName(interpreter))
+	if err := os.WriteFile(scriptPath, []byte(script), 0700); err != nil {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     fmt.Sprintf("failed to write script: %v", err),
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	execCmd, err := interpreterCommand(ctx, interpreter, scriptPath)
+	if err != nil {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     err.Error(),
+			Timestamp: time.Now().Unix(),
+		}
+	}
+	execCmd.Dir = sandboxDir
+	execCmd.Env = minimalEnv()
+
+	limits := ResourceLimits{
+		CPUPercent: intParam(cmd.Params, "cpu_limit_percent", defaultScriptCPUPercent),
+		MemoryMB:   int64(intParam(cmd.Params, "memory_limit_mb", defaultScriptMemoryMB)),
+	}
+
+	sb, err := newSandbox(execCmd, cmd.ID, limits)
+	if err != nil {
+		logger.Warn("running script without resource limits", "error", err)
+	}
+
+	var stdout, stderr limitedBuffer
+	execCmd.Stdout = &stdout
+	execCmd.Stderr = &stderr
+
+	runErr := execCmd.Start()
+	if runErr == nil {
+		if sb != nil {
+			if err := sb.AttachProcess(execCmd.Process.Pid); err != nil {
+				logger.Warn("failed to attach sandbox limits", "error", err)
+			}
+		}
+		runErr = execCmd.Wait()
+	}
+	if sb != nil {
+		sb.Close()
+	}
+
+	data := map[string]interface{}{
+		"stdout":   stdout.String(),
+		"stderr":   stderr.String(),
+		"truncated": stdout.truncated || stderr.truncated,
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "timeout",
+			Error:     "script exceeded its timeout",
+			Data:      data,
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	if runErr != nil {
+		data["exit_error"] = runErr.Error()
+		return api.CommandResult{
+			CommandID: cmd.ID,
+			Status:    "error",
+			Error:     fmt.Sprintf("script execution failed: %v", runErr),
+			Data:      data,
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	return api.CommandResult{
+		CommandID: cmd.ID,
+		Status:    "completed",
+		Output:    stdout.String(),
+		Data:      data,
+		Timestamp: time.Now().Unix(),
+	}
+}
+
+func defaultInterpreter() string {
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "bash"
+}
+
+func scriptFileName(interpreter string) string {
+	if interpreter == "powershell" {
+		return "script.ps1"
+	}
+	return "script.sh"
+}
+
+func interpreterCommand(ctx context.Context, interpreter, scriptPath string) (*exec.Cmd, error) {
+	switch interpreter {
+	case "bash":
+		return exec.CommandContext(ctx, "bash", scriptPath), nil
+	case "powershell":
+		return exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-File", scriptPath), nil
+	default:
+		return nil, fmt.Errorf("unsupported interpreter: %s", interpreter)
+	}
+}
+
+// minimalEnv returns a stripped-down environment for sandboxed scripts so
+// they don't inherit the agent's API key or other secrets from os.Environ.
+func minimalEnv() []string {
+	return []string{
+		"PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin",
+		"HOME=/nonexistent",
+		"LANG=C",
+	}
+}
+
+func intParam(params map[string]interface{}, key string, def int) int {
+	if v, ok := params[key]; ok {
+		switch n := v.(type) {
+		case float64:
+			return int(n)
+		case int:
+			return n
+		}
+	}
+	return def
+}
+
+// limitedBuffer is a bytes.Buffer that stops growing past
+// maxCapturedOutputBytes and records that it truncated output, instead of
+// buffering an unbounded amount of runaway script output.
+type limitedBuffer struct {
+	bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len() >= maxCapturedOutputBytes {
+		b.truncated = true
+		return len(p), nil
+	}
+	n := len(p)
+	remaining := maxCapturedOutputBytes - b.Len()
+	if len(p) > remaining {
+		b.truncated = true
+		p = p[:remaining]
+	}
+	if _, err := b.Buffer.Write(p); err != nil {
+		return 0, err
+	}
+	// Report the full length of p as written, not just the truncated
+	// portion actually buffered: os/exec's output-copy goroutine treats a
+	// short return here as io.ErrShortWrite and aborts the copy, which
+	// would surface a spurious "short write" error from Wait() for any
+	// script whose output exceeds maxCapturedOutputBytes.
+	return n, nil
+}
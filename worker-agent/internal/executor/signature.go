@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+// canonicalCommand is the exact field set and ordering the SaaS signs
+// over: {id, command_type, params, created_at}. encoding/json sorts map
+// keys alphabetically, so Params serializes deterministically without
+// extra bookkeeping here.
+type canonicalCommand struct {
+	ID          string                 `json:"id"`
+	CommandType api.CommandType        `json:"command_type"`
+	Params      map[string]interface{} `json:"params"`
+	CreatedAt   string                 `json:"created_at"`
+}
+
+// canonicalBytes returns the exact byte sequence the SaaS signs for cmd.
+func canonicalBytes(cmd api.Command) ([]byte, error) {
+	return json.Marshal(canonicalCommand{
+		ID:          cmd.ID,
+		CommandType: cmd.CommandType,
+		Params:      cmd.Params,
+		CreatedAt:   cmd.CreatedAt,
+	})
+}
+
+// verifySignature checks cmd.Signature against signingKey. An unconfigured
+// signingKey is treated as "verification disabled" (useful for local
+// development); once a key is configured, unsigned or invalid commands are
+// rejected.
+func verifySignature(cmd api.Command, signingKey ed25519.PublicKey) error {
+	if len(signingKey) == 0 {
+		return nil
+	}
+
+	if cmd.Signature == "" {
+		return fmt.Errorf("command is unsigned")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(cmd.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	payload, err := canonicalBytes(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to build canonical payload: %w", err)
+	}
+
+	if !ed25519.Verify(signingKey, payload, sig) {
+		return fmt.Errorf("signature does not match command payload")
+	}
+
+	return nil
+}
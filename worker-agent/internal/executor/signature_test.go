@@ -0,0 +1,101 @@
+package executor
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+func signedTestCommand(t *testing.T, priv ed25519.PrivateKey) api.Command {
+	t.Helper()
+
+	cmd := api.Command{
+		ID:          "cmd-1",
+		CommandType: api.CommandType("restart"),
+		Params:      map[string]interface{}{"foo": "bar"},
+		CreatedAt:   "2026-01-01T00:00:00Z",
+	}
+
+	payload, err := canonicalBytes(cmd)
+	if err != nil {
+		t.Fatalf("canonicalBytes: %v", err)
+	}
+
+	cmd.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload))
+	return cmd
+}
+
+func TestVerifySignatureNoKeyConfiguredAllowsUnsigned(t *testing.T) {
+	cmd := api.Command{ID: "cmd-1", CommandType: api.CommandType("restart")}
+	if err := verifySignature(cmd, nil); err != nil {
+		t.Errorf("verifySignature with no configured key = %v, want nil", err)
+	}
+}
+
+func TestVerifySignatureRejectsUnsignedWhenKeyConfigured(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	cmd := api.Command{ID: "cmd-1", CommandType: api.CommandType("restart")}
+	if err := verifySignature(cmd, pub); err == nil {
+		t.Error("verifySignature of unsigned command = nil, want error")
+	}
+}
+
+func TestVerifySignatureAcceptsValidSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	cmd := signedTestCommand(t, priv)
+	if err := verifySignature(cmd, pub); err != nil {
+		t.Errorf("verifySignature of correctly signed command = %v, want nil", err)
+	}
+}
+
+func TestVerifySignatureRejectsTamperedParams(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	cmd := signedTestCommand(t, priv)
+	cmd.Params["foo"] = "tampered"
+
+	if err := verifySignature(cmd, pub); err == nil {
+		t.Error("verifySignature of tampered command = nil, want error")
+	}
+}
+
+func TestVerifySignatureRejectsWrongKey(t *testing.T) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	cmd := signedTestCommand(t, priv)
+	if err := verifySignature(cmd, otherPub); err == nil {
+		t.Error("verifySignature against mismatched key = nil, want error")
+	}
+}
+
+func TestVerifySignatureRejectsInvalidEncoding(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	cmd := api.Command{ID: "cmd-1", CommandType: api.CommandType("restart"), Signature: "not-base64!!"}
+	if err := verifySignature(cmd, pub); err == nil {
+		t.Error("verifySignature with malformed signature = nil, want error")
+	}
+}
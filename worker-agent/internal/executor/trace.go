@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// loggerCtxKey is the context.Context key a command's sub-logger is stored
+// under, so executeXxx handlers can pull it out without threading an extra
+// parameter through the Handler signature.
+type loggerCtxKey struct{}
+
+// withLogger returns a child context carrying logger, retrievable via
+// loggerFromContext.
+func withLogger(ctx context.Context, logger hclog.Logger) context.Context {
+	return context.WithValue(ctx, loggerCtxKey{}, logger)
+}
+
+// loggerFromContext returns the logger attached by withLogger, or a null
+// logger if none was attached (e.g. in tests that call an executeXxx method
+// directly).
+func loggerFromContext(ctx context.Context) hclog.Logger {
+	if logger, ok := ctx.Value(loggerCtxKey{}).(hclog.Logger); ok {
+		return logger
+	}
+	return hclog.NewNullLogger()
+}
+
+// newTraceID generates a short random identifier to correlate a single
+// command's log lines, independent of the command's own ID (which is
+// assigned by the SaaS and may be reused across retries).
+func newTraceID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "unavailable"
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -1,88 +1,68 @@
-package executor
-
-import (
-	"context"
-	"fmt"
-	"sync"
-	"time"
-)
-
-// TradingService manages trading operations
-type TradingService struct {
-	mu         sync.RWMutex
-	isRunning  bool
-	startedAt  time.Time
-	configPath string
-}
-
-// NewTradingService creates a new trading service
-func NewTradingService() *TradingService {
-	return &TradingService{
-		isRunning: false,
-	}
-}
-
-// Start starts the trading service
-func (ts *TradingService) Start(ctx context.Context, configPath string) error {
-	ts.mu.Lock()
-	defer ts.mu.Unlock()
-
-	if ts.isRunning {
-		return fmt.Errorf("trading service already running")
-	}
-
-	// TODO: Implement actual trading logic here
-	// For now, just simulate starting
-	ts.isRunning = true
-	ts.startedAt = time.Now()
-	ts.configPath = configPath
-
-	return nil
-}
-
-// Stop stops the trading service
-func (ts *TradingService) Stop(ctx context.Context) error {
-	ts.mu.Lock()
-	defer ts.mu.Unlock()
-
-	if !ts.isRunning {
-		return fmt.Errorf("trading service not running")
-	}
-
-	// TODO: Implement actual stop logic
-	// For now, just simulate stopping
-	ts.isRunning = false
-
-	return nil
-}
-
-// GetStatus returns the current trading status
-func (ts *TradingService) GetStatus() string {
-	ts.mu.RLock()
-	defer ts.mu.RUnlock()
-
-	if ts.isRunning {
-		return "running"
-	}
-	return "stopped"
-}
-
-// GetUptime returns how long trading has been running
-func (ts *TradingService) GetUptime() string {
-	ts.mu.RLock()
-	defer ts.mu.RUnlock()
-
-	if !ts.isRunning {
-		return "0s"
-	}
-
-	return time.Since(ts.startedAt).String()
-}
-
-// IsRunning returns whether trading is currently running
-func (ts *TradingService) IsRunning() bool {
-	ts.mu.RLock()
-	defer ts.mu.RUnlock()
-
-	return ts.isRunning
-}
+package executor
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/finetunelab/worker-agent/internal/trading"
+)
+
+// TradingService is the executor's handle onto the trading engine. It
+// delegates to trading.Service; see that package for the Strategy/Broker/
+// MarketDataFeed subsystem.
+type TradingService struct {
+	Logger hclog.Logger
+
+	svc *trading.Service
+}
+
+// NewTradingService creates a new trading service, logging via logger (a
+// nil logger discards all output).
+func NewTradingService(logger hclog.Logger) *TradingService {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+	return &TradingService{Logger: logger, svc: trading.NewService(logger)}
+}
+
+// Start starts the trading service, loading configPath as a trading.Config.
+func (ts *TradingService) Start(ctx context.Context, configPath string) error {
+	return ts.svc.Start(ctx, configPath)
+}
+
+// Stop stops the trading service
+func (ts *TradingService) Stop(ctx context.Context) error {
+	return ts.svc.Stop(ctx)
+}
+
+// GetStatus returns the current trading status
+func (ts *TradingService) GetStatus() string {
+	return ts.svc.GetStatus()
+}
+
+// GetUptime returns how long trading has been running
+func (ts *TradingService) GetUptime() string {
+	return ts.svc.GetUptime()
+}
+
+// IsRunning returns whether trading is currently running
+func (ts *TradingService) IsRunning() bool {
+	return ts.svc.IsRunning()
+}
+
+// ConfigPath returns the trading config path from the most recent Start
+// call, or "" if trading has never been started.
+func (ts *TradingService) ConfigPath() string {
+	return ts.svc.ConfigPath()
+}
+
+// RecentOrders returns the most recently placed orders, oldest first.
+func (ts *TradingService) RecentOrders() []trading.Order {
+	return ts.svc.RecentOrders()
+}
+
+// RecentFills returns the most recently received fills, oldest first.
+func (ts *TradingService) RecentFills() []trading.Fill {
+	return ts.svc.RecentFills()
+}
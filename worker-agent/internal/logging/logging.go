@@ -0,0 +1,92 @@
+// Package logging configures the agent's structured logger and keeps a
+// ring buffer of recent log records so they can be retrieved without SSH
+// access to the host, either via the collect_diagnostics command or the
+// agent's periodic push to the SaaS.
+package logging
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// defaultRingBufferSize is the number of recent log lines retained in
+// memory for diagnostics.
+const defaultRingBufferSize = 1000
+
+// Record is a single captured log line.
+type Record struct {
+	Time time.Time `json:"time"`
+	Line string    `json:"line"`
+}
+
+// RingBuffer is an io.Writer that retains only the last N lines written to
+// it, discarding older ones.
+type RingBuffer struct {
+	mu      sync.Mutex
+	records []Record
+	max     int
+}
+
+// NewRingBuffer creates a ring buffer retaining up to max lines.
+func NewRingBuffer(max int) *RingBuffer {
+	return &RingBuffer{max: max}
+}
+
+// Write implements io.Writer, appending a record for each call.
+func (r *RingBuffer) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\n")
+
+	r.mu.Lock()
+	r.records = append(r.records, Record{Time: time.Now(), Line: line})
+	if len(r.records) > r.max {
+		r.records = r.records[len(r.records)-r.max:]
+	}
+	r.mu.Unlock()
+
+	return len(p), nil
+}
+
+// Snapshot returns a copy of the currently retained records, oldest first.
+func (r *RingBuffer) Snapshot() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Record, len(r.records))
+	copy(out, r.records)
+	return out
+}
+
+// Options configures New.
+type Options struct {
+	Name   string
+	Level  string // debug, info, warn, error
+	JSON   bool   // JSON output, suited to log aggregation in production
+	Output io.Writer
+}
+
+// New builds the agent's root hclog.Logger plus the ring buffer feeding
+// its diagnostics snapshot. All component loggers should be created via
+// logger.Named or logger.With on the returned Logger so worker_id,
+// component, etc. are consistently attached.
+func New(opts Options) (hclog.Logger, *RingBuffer) {
+	ring := NewRingBuffer(defaultRingBufferSize)
+
+	output := opts.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:       opts.Name,
+		Level:      hclog.LevelFromString(opts.Level),
+		JSONFormat: opts.JSON,
+		Output:     io.MultiWriter(output, ring),
+	})
+
+	return logger, ring
+}
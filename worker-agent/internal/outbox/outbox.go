@@ -0,0 +1,276 @@
+// Package outbox implements a durable on-disk queue that buffers payloads
+// destined for the SaaS (command results, metrics batches) while it is
+// unreachable, so results survive network outages and agent restarts.
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	minRetryBackoff = 2 * time.Second
+	maxRetryBackoff = 2 * time.Minute
+	drainInterval   = 5 * time.Second
+)
+
+// SendFunc delivers a single queued item to the SaaS. A non-nil error
+// leaves the item queued for retry.
+type SendFunc func(ctx context.Context, kind string, payload json.RawMessage) error
+
+// item is one queued payload, persisted as a single JSON line.
+type item struct {
+	ID       int64           `json:"id"`
+	Kind     string          `json:"kind"`
+	Payload  json.RawMessage `json:"payload"`
+	Attempts int             `json:"attempts"`
+}
+
+// Outbox is a durable, size-bounded FIFO queue backed by a JSON-lines
+// file. Items are held in memory and the whole file is rewritten from them
+// on every Enqueue and every successful delivery.
+type Outbox struct {
+	path     string
+	maxBytes int64
+	send     SendFunc
+
+	mu      sync.Mutex
+	items   []*item
+	nextID  int64
+	dirty   bool
+}
+
+// Open loads (or creates) the outbox file at path and returns an Outbox
+// ready to enqueue items and drain them via send once Run is started.
+// maxBytes bounds the on-disk size; once exceeded, the oldest queued items
+// are dropped to make room for new ones.
+func Open(path string, maxBytes int64, send SendFunc) (*Outbox, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	o := &Outbox{
+		path:     path,
+		maxBytes: maxBytes,
+		send:     send,
+	}
+
+	if err := o.load(); err != nil {
+		return nil, fmt.Errorf("failed to load outbox: %w", err)
+	}
+
+	return o, nil
+}
+
+// load reads any previously-persisted items from disk, skipping malformed
+// lines (e.g. from a crash mid-write) rather than failing outright.
+func (o *Outbox) load() error {
+	f, err := os.Open(o.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var it item
+		if err := json.Unmarshal(scanner.Bytes(), &it); err != nil {
+			log.Printf("[Outbox] Skipping malformed entry: %v", err)
+			continue
+		}
+		o.items = append(o.items, &it)
+		if it.ID >= o.nextID {
+			o.nextID = it.ID + 1
+		}
+	}
+
+	log.Printf("[Outbox] Loaded %d pending item(s) from %s", len(o.items), o.path)
+	return scanner.Err()
+}
+
+// Enqueue durably queues a payload for delivery. It is safe to call
+// concurrently with Run.
+func (o *Outbox) Enqueue(kind string, payload interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox payload: %w", err)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.items = append(o.items, &item{ID: o.nextID, Kind: kind, Payload: raw})
+	o.nextID++
+	o.dirty = true
+
+	o.evictLocked()
+
+	return o.persistLocked()
+}
+
+// evictLocked drops the oldest queued items until the persisted size would
+// fit within maxBytes. Must be called with o.mu held.
+func (o *Outbox) evictLocked() {
+	if o.maxBytes <= 0 {
+		return
+	}
+
+	for len(o.items) > 0 && o.sizeLocked() > o.maxBytes {
+		dropped := o.items[0]
+		o.items = o.items[1:]
+		log.Printf("[Outbox] Max size exceeded, dropping oldest queued %s item %d", dropped.Kind, dropped.ID)
+	}
+}
+
+func (o *Outbox) sizeLocked() int64 {
+	var size int64
+	for _, it := range o.items {
+		raw, _ := json.Marshal(it)
+		size += int64(len(raw)) + 1
+	}
+	return size
+}
+
+// persistLocked rewrites the outbox file from the in-memory queue. Must be
+// called with o.mu held.
+func (o *Outbox) persistLocked() error {
+	tmpPath := o.path + ".tmp"
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	for _, it := range o.items {
+		raw, err := json.Marshal(it)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := w.Write(raw); err != nil {
+			f.Close()
+			return err
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	o.dirty = false
+	return os.Rename(tmpPath, o.path)
+}
+
+// Run drains the queue until ctx is canceled, retrying failed deliveries
+// with exponential backoff and jitter. It blocks and should be run in its
+// own goroutine.
+func (o *Outbox) Run(ctx context.Context) {
+	backoff := minRetryBackoff
+
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := o.drainOne(ctx)
+			if err != nil {
+				log.Printf("[Outbox] Delivery failed, will retry: %v", err)
+				backoff = nextBackoff(backoff)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(jitter(backoff)):
+				}
+				continue
+			}
+			if ok {
+				backoff = minRetryBackoff
+			}
+		}
+	}
+}
+
+// drainOne attempts to deliver the oldest queued item. Returns ok=true if
+// an item was successfully delivered and removed.
+func (o *Outbox) drainOne(ctx context.Context) (bool, error) {
+	o.mu.Lock()
+	if len(o.items) == 0 {
+		o.mu.Unlock()
+		return false, nil
+	}
+	it := o.items[0]
+	o.mu.Unlock()
+
+	if err := o.send(ctx, it.Kind, it.Payload); err != nil {
+		o.mu.Lock()
+		it.Attempts++
+		o.dirty = true
+		o.mu.Unlock()
+		return false, fmt.Errorf("send %s item %d (attempt %d): %w", it.Kind, it.ID, it.Attempts, err)
+	}
+
+	o.mu.Lock()
+	o.removeLocked(it.ID)
+	err := o.persistLocked()
+	o.mu.Unlock()
+
+	return true, err
+}
+
+// removeLocked drops the item with the given ID from the queue, if still
+// present. It must look the item up by ID rather than assuming it's still
+// at the front: a concurrent Enqueue can run evictLocked while send is in
+// flight and drop the front item out from under us. Must be called with
+// o.mu held.
+func (o *Outbox) removeLocked(id int64) {
+	for i, it := range o.items {
+		if it.ID == id {
+			o.items = append(o.items[:i], o.items[i+1:]...)
+			o.dirty = true
+			return
+		}
+	}
+}
+
+// Len returns the number of items currently queued.
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.items)
+}
+
+func nextBackoff(cur time.Duration) time.Duration {
+	next := cur * 2
+	if next > maxRetryBackoff {
+		next = maxRetryBackoff
+	}
+	return next
+}
+
+func jitter(base time.Duration) time.Duration {
+	return base + time.Duration(rand.Int63n(int64(base)/2+1))
+}
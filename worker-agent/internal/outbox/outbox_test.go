@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+// TestOutboxDrainOneRemovesByIDNotIndex guards against a regression where
+// drainOne removed whatever was at items[0] after a successful send,
+// instead of the item it actually delivered. If a concurrent Enqueue
+// evicts the in-flight item from the front while send is still running,
+// blindly dropping index 0 on success silently discards whatever new item
+// has taken its place.
+func TestOutboxDrainOneRemovesByIDNotIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	sendStarted := make(chan struct{})
+	sendGate := make(chan struct{})
+
+	ob, err := Open(path, 0, func(ctx context.Context, kind string, payload json.RawMessage) error {
+		close(sendStarted)
+		<-sendGate
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if err := ob.Enqueue("first", map[string]string{"v": "first"}); err != nil {
+		t.Fatalf("Enqueue first: %v", err)
+	}
+
+	drainDone := make(chan struct{})
+	var ok bool
+	var drainErr error
+	go func() {
+		ok, drainErr = ob.drainOne(context.Background())
+		close(drainDone)
+	}()
+
+	<-sendStarted
+
+	// Simulate a concurrent Enqueue evicting the in-flight item out from
+	// under the send, the way evictLocked would under size pressure.
+	ob.mu.Lock()
+	ob.items = ob.items[1:]
+	ob.mu.Unlock()
+
+	if err := ob.Enqueue("second", map[string]string{"v": "second"}); err != nil {
+		t.Fatalf("Enqueue second: %v", err)
+	}
+
+	close(sendGate)
+	<-drainDone
+
+	if drainErr != nil {
+		t.Fatalf("drainOne returned error: %v", drainErr)
+	}
+	if !ok {
+		t.Fatalf("drainOne ok = false, want true")
+	}
+
+	if got := ob.Len(); got != 1 {
+		t.Fatalf("Len() = %d, want 1", got)
+	}
+
+	ob.mu.Lock()
+	kind := ob.items[0].Kind
+	ob.mu.Unlock()
+	if kind != "second" {
+		t.Errorf("remaining item kind = %q, want %q (delivered item was removed by index instead of ID)", kind, "second")
+	}
+}
@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"filippo.io/age"
+)
+
+// ageIdentityEnvVar overrides the default identity file location used to
+// decrypt an age-encrypted config file.
+const ageIdentityEnvVar = "FINETUNELAB_AGE_IDENTITY"
+
+// ageMagic is the start of every age-encrypted file, used to detect
+// whether a config file needs decrypting at all.
+var ageMagic = []byte("age-encryption.org/v1")
+
+// IsAgeEncrypted reports whether data looks like an age-encrypted file.
+func IsAgeEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, ageMagic)
+}
+
+// DecryptConfig decrypts an age-encrypted config file using the identity
+// at FINETUNELAB_AGE_IDENTITY, or configPath+".age-identity" if that env
+// var isn't set. Data that isn't age-encrypted (see IsAgeEncrypted) is
+// returned unchanged, so callers can pass any config file contents.
+func DecryptConfig(data []byte, configPath string) ([]byte, error) {
+	if !IsAgeEncrypted(data) {
+		return data, nil
+	}
+
+	identityPath := os.Getenv(ageIdentityEnvVar)
+	if identityPath == "" {
+		identityPath = configPath + ".age-identity"
+	}
+
+	identityData, err := os.ReadFile(identityPath)
+	if err != nil {
+		return nil, fmt.Errorf("config is age-encrypted but identity file %q could not be read: %w", identityPath, err)
+	}
+
+	identities, err := age.ParseIdentities(bytes.NewReader(identityData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age identity: %w", err)
+	}
+
+	r, err := age.Decrypt(bytes.NewReader(data), identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt config: %w", err)
+	}
+
+	var out bytes.Buffer
+	if _, err := out.ReadFrom(r); err != nil {
+		return nil, fmt.Errorf("failed to read decrypted config: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
+
+// EncryptConfigIfConfigured encrypts data for the recipients listed in
+// configPath+".age-recipients", if that file exists. Hosts that don't use
+// age-based encryption (the common case) never create that file, so this
+// is a no-op for them.
+func EncryptConfigIfConfigured(data []byte, configPath string) ([]byte, error) {
+	recipientsPath := configPath + ".age-recipients"
+
+	recipientsData, err := os.ReadFile(recipientsPath)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %w", recipientsPath, err)
+	}
+
+	recipients, err := age.ParseRecipients(bytes.NewReader(recipientsData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse age recipients: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, recipients...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start age encryption: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to write encrypted config: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize encrypted config: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
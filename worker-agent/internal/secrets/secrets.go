@@ -0,0 +1,90 @@
+// Package secrets resolves "keyring:service/account" references stored in
+// config files into real secret values, backed by the OS keyring with an
+// environment variable fallback for hosts without one (containers, CI).
+package secrets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zalando/go-keyring"
+)
+
+// RefPrefix marks a config value as a keyring reference rather than a
+// literal secret.
+const RefPrefix = "keyring:"
+
+// IsRef reports whether value is a "keyring:service/account" reference.
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, RefPrefix)
+}
+
+// Provider resolves a config value into a secret. Literal (non-reference)
+// values are returned unchanged so callers can treat Resolve as a no-op
+// pass-through for plaintext config during local development.
+type Provider interface {
+	Resolve(value string) (string, error)
+}
+
+// KeyringEnvProvider resolves keyring: references against the OS keyring
+// (Windows Credential Manager, macOS Keychain, Secret Service on Linux),
+// falling back to an environment variable when the keyring is unavailable
+// (e.g. headless Linux hosts without a Secret Service daemon).
+type KeyringEnvProvider struct{}
+
+// Resolve implements Provider.
+func (KeyringEnvProvider) Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+
+	service, account, err := parseRef(value)
+	if err != nil {
+		return "", err
+	}
+
+	secret, keyringErr := keyring.Get(service, account)
+	if keyringErr == nil {
+		return secret, nil
+	}
+
+	envVar := envVarName(service, account)
+	if envVal := os.Getenv(envVar); envVal != "" {
+		return envVal, nil
+	}
+
+	return "", fmt.Errorf("failed to resolve %q: keyring lookup failed (%v) and %s is not set", value, keyringErr, envVar)
+}
+
+// Store writes value into the OS keyring under service/account and returns
+// the "keyring:service/account" reference to persist in config in place
+// of the literal secret.
+func Store(service, account, value string) (string, error) {
+	if err := keyring.Set(service, account, value); err != nil {
+		return "", fmt.Errorf("failed to store secret in OS keyring: %w", err)
+	}
+	return RefPrefix + service + "/" + account, nil
+}
+
+func parseRef(ref string) (service, account string, err error) {
+	target := strings.TrimPrefix(ref, RefPrefix)
+	parts := strings.SplitN(target, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid secret reference %q, expected keyring:<service>/<account>", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// envVarName derives the environment variable fallback name for a
+// service/account pair, e.g. "finetunelab"/"worker-agent" ->
+// "FINETUNELAB_WORKER_AGENT".
+func envVarName(service, account string) string {
+	raw := strings.ToUpper(service + "_" + account)
+	return strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, raw)
+}
@@ -0,0 +1,308 @@
+// Package spool persists every executed command and its result to disk, so
+// they survive an agent restart and can be listed, replayed for
+// diagnostics, or garbage-collected. The design mirrors percona-agent's
+// data spool: flat JSON files under a directory, with a trash/ subdir that
+// purged files are moved into instead of being deleted outright.
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+// trashDirName is the subdirectory purged spool files are moved into.
+const trashDirName = "trash"
+
+// safeCommandIDPattern restricts the command ID used to build spool file
+// names to characters that can't contain path separators or traversal
+// segments. cmd.ID arrives over the network (see pkg/api.Command) and
+// signature verification of it is optional, so it must not be trusted as
+// a path component as-is.
+var safeCommandIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Config bounds how much the spool is allowed to grow before Sweep starts
+// purging the oldest records. A zero value for any limit disables it.
+type Config struct {
+	Dir           string
+	MaxAge        time.Duration
+	MaxSize       int64 // total bytes across all spooled files
+	MaxFiles      int
+	SweepInterval time.Duration
+}
+
+// Record is a single spooled command and its result.
+type Record struct {
+	CommandID string            `json:"command_id"`
+	Command   api.Command       `json:"command"`
+	Result    api.CommandResult `json:"result"`
+	StoredAt  time.Time         `json:"stored_at"`
+}
+
+// Report summarizes the result of a Sweep.
+type Report struct {
+	Purged int           `json:"purged"`
+	Age    time.Duration `json:"age"`  // age of the oldest record remaining in the spool
+	Size   int64         `json:"size"` // total bytes remaining in the spool
+	Files  int           `json:"files"`
+}
+
+// Spooler writes executed commands and results to disk and enforces Config's
+// retention limits.
+type Spooler struct {
+	cfgMu  sync.RWMutex
+	cfg    Config
+	logger hclog.Logger
+}
+
+// Open creates the spool directory (and its trash subdirectory) if needed
+// and returns a ready-to-use Spooler.
+func Open(cfg Config, logger hclog.Logger) (*Spooler, error) {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(cfg.Dir, trashDirName), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spool trash directory: %w", err)
+	}
+
+	return &Spooler{cfg: cfg, logger: logger}, nil
+}
+
+// config returns a copy of the current Config, safe to read without
+// further locking.
+func (s *Spooler) config() Config {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg
+}
+
+// Reconfigure updates the spool's retention limits and sweep interval in
+// place, e.g. for a hot config reload. Dir is not changed: the spool
+// directory is fixed for the lifetime of the Spooler.
+func (s *Spooler) Reconfigure(cfg Config) {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	cfg.Dir = s.cfg.Dir
+	s.cfg = cfg
+}
+
+// Write persists cmd and its result as a new spool file.
+func (s *Spooler) Write(cmd api.Command, result api.CommandResult) error {
+	if !safeCommandIDPattern.MatchString(cmd.ID) {
+		return fmt.Errorf("invalid command id %q for spool file name", cmd.ID)
+	}
+
+	record := Record{
+		CommandID: cmd.ID,
+		Command:   cmd,
+		Result:    result,
+		StoredAt:  time.Now(),
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spool record: %w", err)
+	}
+
+	path := filepath.Join(s.config().Dir, fmt.Sprintf("%d-%s.json", record.StoredAt.UnixNano(), cmd.ID))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write spool record: %w", err)
+	}
+
+	return nil
+}
+
+// List returns every spooled record, oldest first.
+func (s *Spooler) List() ([]Record, error) {
+	entries, err := s.readRecords(s.config().Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].record.StoredAt.Before(entries[j].record.StoredAt)
+	})
+
+	records := make([]Record, len(entries))
+	for i, e := range entries {
+		records[i] = e.record
+	}
+	return records, nil
+}
+
+// Cursor returns an opaque marker identifying the most recently written
+// spool record, e.g. for a restart handoff file to record where the spool
+// stood at the time of the restart. An empty spool returns "".
+func (s *Spooler) Cursor() (string, error) {
+	records, err := s.List()
+	if err != nil {
+		return "", err
+	}
+	if len(records) == 0 {
+		return "", nil
+	}
+	last := records[len(records)-1]
+	return fmt.Sprintf("%s@%s", last.CommandID, last.StoredAt.Format(time.RFC3339Nano)), nil
+}
+
+// spoolFile pairs a parsed Record with the path it was read from, so Sweep
+// can move the underlying file without re-reading it.
+type spoolFile struct {
+	path   string
+	size   int64
+	record Record
+}
+
+// readRecords parses every *.json file directly under dir (non-recursive,
+// so it never descends into trash/).
+func (s *Spooler) readRecords(dir string) ([]spoolFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	var files []spoolFile
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			s.logger.Warn("failed to read spool file, skipping", "path", path, "error", err)
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			s.logger.Warn("failed to parse spool file, skipping", "path", path, "error", err)
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		files = append(files, spoolFile{path: path, size: info.Size(), record: record})
+	}
+
+	return files, nil
+}
+
+// Sweep enforces MaxAge, MaxSize, and MaxFiles by moving the oldest
+// offending spool files into trash/, and returns a report describing what
+// remains.
+func (s *Spooler) Sweep() (Report, error) {
+	cfg := s.config()
+
+	files, err := s.readRecords(cfg.Dir)
+	if err != nil {
+		return Report{}, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].record.StoredAt.Before(files[j].record.StoredAt)
+	})
+
+	var totalSize int64
+	for _, f := range files {
+		totalSize += f.size
+	}
+
+	purged := 0
+	now := time.Now()
+	i := 0
+	for i < len(files) {
+		f := files[i]
+
+		exceedsAge := cfg.MaxAge > 0 && now.Sub(f.record.StoredAt) > cfg.MaxAge
+		exceedsSize := cfg.MaxSize > 0 && totalSize > cfg.MaxSize
+		exceedsCount := cfg.MaxFiles > 0 && len(files)-purged > cfg.MaxFiles
+
+		if !exceedsAge && !exceedsSize && !exceedsCount {
+			break
+		}
+
+		if err := s.moveToTrash(f.path); err != nil {
+			s.logger.Warn("failed to purge spool file", "path", f.path, "error", err)
+			i++
+			continue
+		}
+
+		totalSize -= f.size
+		purged++
+		i++
+	}
+
+	remaining := files[i:]
+	report := Report{
+		Purged: purged,
+		Size:   totalSize,
+		Files:  len(remaining),
+	}
+	if len(remaining) > 0 {
+		report.Age = now.Sub(remaining[0].record.StoredAt)
+	}
+
+	if purged > 0 {
+		s.logger.Info("swept spool", "purged", purged, "remaining_files", report.Files, "remaining_size", report.Size)
+	}
+
+	return report, nil
+}
+
+func (s *Spooler) moveToTrash(path string) error {
+	dst := filepath.Join(s.config().Dir, trashDirName, filepath.Base(path))
+	return os.Rename(path, dst)
+}
+
+// Run periodically calls Sweep until ctx is canceled. It should be run in
+// its own goroutine for the lifetime of the agent; a zero SweepInterval
+// disables the background sweeper. Reconfigure may change SweepInterval
+// while Run is active: each tick re-reads the current interval and resets
+// the ticker to it, so a changed nonzero interval takes effect from the
+// next tick on, and a zero interval skips sweeps (without tearing down the
+// goroutine) until a nonzero one is set again.
+func (s *Spooler) Run(ctx context.Context) {
+	interval := s.config().SweepInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := s.config().SweepInterval
+			if current <= 0 {
+				continue
+			}
+			if current != interval {
+				interval = current
+				ticker.Reset(interval)
+			}
+			if _, err := s.Sweep(); err != nil {
+				s.logger.Warn("spool sweep failed", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Broker abstracts exchange connectivity: placing, canceling, and querying
+// orders. Fills reports executions asynchronously so a Service can dispatch
+// them to the owning Strategy's OnFill as they arrive.
+type Broker interface {
+	PlaceOrder(ctx context.Context, order Order) (orderID string, err error)
+	CancelOrder(ctx context.Context, orderID string) error
+	QueryOrder(ctx context.Context, orderID string) (OrderStatus, error)
+	Fills() <-chan Fill
+}
+
+// BrokerFactory constructs a Broker from its configured params.
+type BrokerFactory func(params map[string]interface{}) (Broker, error)
+
+var (
+	brokerMu       sync.RWMutex
+	brokerRegistry = make(map[string]BrokerFactory)
+)
+
+// RegisterBroker adds a named broker to the registry, replacing any
+// previously registered factory for that name.
+func RegisterBroker(name string, factory BrokerFactory) {
+	brokerMu.Lock()
+	defer brokerMu.Unlock()
+	brokerRegistry[name] = factory
+}
+
+// NewBroker constructs the named broker with params.
+func NewBroker(name string, params map[string]interface{}) (Broker, error) {
+	brokerMu.RLock()
+	factory, ok := brokerRegistry[name]
+	brokerMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown broker %q", name)
+	}
+	return factory(params)
+}
@@ -0,0 +1,44 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// MarketDataFeed streams price updates for a set of symbols.
+type MarketDataFeed interface {
+	// Subscribe streams MarketData for symbols to ch until ctx is
+	// canceled or the feed hits a fatal error. It blocks for the
+	// lifetime of the subscription, so callers run it in its own
+	// goroutine.
+	Subscribe(ctx context.Context, symbols []string, ch chan<- MarketData) error
+}
+
+// MarketDataFeedFactory constructs a MarketDataFeed from its configured
+// params.
+type MarketDataFeedFactory func(params map[string]interface{}) (MarketDataFeed, error)
+
+var (
+	feedMu       sync.RWMutex
+	feedRegistry = make(map[string]MarketDataFeedFactory)
+)
+
+// RegisterFeed adds a named feed to the registry, replacing any previously
+// registered factory for that name.
+func RegisterFeed(name string, factory MarketDataFeedFactory) {
+	feedMu.Lock()
+	defer feedMu.Unlock()
+	feedRegistry[name] = factory
+}
+
+// NewFeed constructs the named feed with params.
+func NewFeed(name string, params map[string]interface{}) (MarketDataFeed, error) {
+	feedMu.RLock()
+	factory, ok := feedRegistry[name]
+	feedMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown market data feed %q", name)
+	}
+	return factory(params)
+}
@@ -0,0 +1,108 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	RegisterBroker("paper", newPaperBroker)
+}
+
+// PaperBroker is an in-memory broker for paper trading and tests: every
+// order fills immediately and in full at its requested price (or the last
+// known market price for market orders), with no order book or slippage
+// simulation.
+type PaperBroker struct {
+	nextID int64
+	fills  chan Fill
+
+	mu          sync.RWMutex
+	lastPrice   map[string]float64
+	orderStatus map[string]OrderStatus
+}
+
+func newPaperBroker(params map[string]interface{}) (Broker, error) {
+	return &PaperBroker{
+		fills:       make(chan Fill, 64),
+		lastPrice:   make(map[string]float64),
+		orderStatus: make(map[string]OrderStatus),
+	}, nil
+}
+
+// UpdatePrice records the last known market price for symbol, used to fill
+// market orders. Feeds that wrap a PaperBroker should call this for every
+// tick they deliver.
+func (b *PaperBroker) UpdatePrice(symbol string, price float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastPrice[symbol] = price
+}
+
+// PlaceOrder implements Broker.
+func (b *PaperBroker) PlaceOrder(ctx context.Context, order Order) (string, error) {
+	price := order.Price
+	if order.Type == OrderTypeMarket {
+		b.mu.RLock()
+		price = b.lastPrice[order.Symbol]
+		b.mu.RUnlock()
+	}
+	if price <= 0 {
+		return "", fmt.Errorf("no price available to fill order for %s", order.Symbol)
+	}
+
+	orderID := fmt.Sprintf("paper-%d", atomic.AddInt64(&b.nextID, 1))
+
+	b.mu.Lock()
+	b.orderStatus[orderID] = OrderStatusFilled
+	b.mu.Unlock()
+
+	select {
+	case b.fills <- Fill{
+		OrderID:  orderID,
+		Symbol:   order.Symbol,
+		Side:     order.Side,
+		Quantity: order.Quantity,
+		Price:    price,
+		Time:     time.Now(),
+	}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+
+	return orderID, nil
+}
+
+// CancelOrder implements Broker. Paper orders fill synchronously in
+// PlaceOrder, so there is never anything left to cancel.
+func (b *PaperBroker) CancelOrder(ctx context.Context, orderID string) error {
+	b.mu.RLock()
+	status, ok := b.orderStatus[orderID]
+	b.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown order %q", orderID)
+	}
+	if status == OrderStatusFilled {
+		return fmt.Errorf("order %q already filled", orderID)
+	}
+	return nil
+}
+
+// QueryOrder implements Broker.
+func (b *PaperBroker) QueryOrder(ctx context.Context, orderID string) (OrderStatus, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	status, ok := b.orderStatus[orderID]
+	if !ok {
+		return "", fmt.Errorf("unknown order %q", orderID)
+	}
+	return status, nil
+}
+
+// Fills implements Broker.
+func (b *PaperBroker) Fills() <-chan Fill {
+	return b.fills
+}
@@ -0,0 +1,114 @@
+package trading
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPaperBrokerPlaceOrderLimit(t *testing.T) {
+	b, err := NewBroker("paper", nil)
+	if err != nil {
+		t.Fatalf("NewBroker: %v", err)
+	}
+
+	orderID, err := b.PlaceOrder(context.Background(), Order{
+		Symbol:   "BTC-USD",
+		Side:     OrderSideBuy,
+		Type:     OrderTypeLimit,
+		Quantity: 1,
+		Price:    100,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	status, err := b.QueryOrder(context.Background(), orderID)
+	if err != nil {
+		t.Fatalf("QueryOrder: %v", err)
+	}
+	if status != OrderStatusFilled {
+		t.Errorf("status = %q, want %q", status, OrderStatusFilled)
+	}
+
+	select {
+	case fill := <-b.Fills():
+		if fill.OrderID != orderID || fill.Price != 100 || fill.Quantity != 1 {
+			t.Errorf("unexpected fill: %+v", fill)
+		}
+	default:
+		t.Fatal("expected a fill to be queued")
+	}
+}
+
+func TestPaperBrokerPlaceOrderMarketUsesLastPrice(t *testing.T) {
+	b, err := NewBroker("paper", nil)
+	if err != nil {
+		t.Fatalf("NewBroker: %v", err)
+	}
+	paper := b.(*PaperBroker)
+	paper.UpdatePrice("ETH-USD", 42.5)
+
+	orderID, err := b.PlaceOrder(context.Background(), Order{
+		Symbol:   "ETH-USD",
+		Side:     OrderSideSell,
+		Type:     OrderTypeMarket,
+		Quantity: 2,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	fill := <-b.Fills()
+	if fill.OrderID != orderID || fill.Price != 42.5 {
+		t.Errorf("unexpected fill: %+v", fill)
+	}
+}
+
+func TestPaperBrokerPlaceOrderMarketNoPriceFails(t *testing.T) {
+	b, err := NewBroker("paper", nil)
+	if err != nil {
+		t.Fatalf("NewBroker: %v", err)
+	}
+
+	if _, err := b.PlaceOrder(context.Background(), Order{
+		Symbol:   "UNKNOWN",
+		Side:     OrderSideBuy,
+		Type:     OrderTypeMarket,
+		Quantity: 1,
+	}); err == nil {
+		t.Fatal("expected an error when no price is available")
+	}
+}
+
+func TestPaperBrokerCancelAlreadyFilledFails(t *testing.T) {
+	b, err := NewBroker("paper", nil)
+	if err != nil {
+		t.Fatalf("NewBroker: %v", err)
+	}
+
+	orderID, err := b.PlaceOrder(context.Background(), Order{
+		Symbol:   "BTC-USD",
+		Side:     OrderSideBuy,
+		Type:     OrderTypeLimit,
+		Quantity: 1,
+		Price:    100,
+	})
+	if err != nil {
+		t.Fatalf("PlaceOrder: %v", err)
+	}
+
+	if err := b.CancelOrder(context.Background(), orderID); err == nil {
+		t.Fatal("expected canceling an already-filled order to fail")
+	}
+}
+
+func TestPaperBrokerQueryUnknownOrderFails(t *testing.T) {
+	b, err := NewBroker("paper", nil)
+	if err != nil {
+		t.Fatalf("NewBroker: %v", err)
+	}
+
+	if _, err := b.QueryOrder(context.Background(), "does-not-exist"); err == nil {
+		t.Fatal("expected querying an unknown order to fail")
+	}
+}
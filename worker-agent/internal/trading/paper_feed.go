@@ -0,0 +1,68 @@
+package trading
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+func init() {
+	RegisterFeed("paper", newPaperFeed)
+}
+
+// paperFeed is a synthetic MarketDataFeed for paper trading and tests. It
+// generates a random walk around startPrice for each subscribed symbol,
+// with no connection to any real exchange.
+type paperFeed struct {
+	startPrice float64
+	interval   time.Duration
+}
+
+func newPaperFeed(params map[string]interface{}) (MarketDataFeed, error) {
+	f := &paperFeed{
+		startPrice: 100,
+		interval:   time.Second,
+	}
+
+	if v, ok := params["start_price"].(float64); ok && v > 0 {
+		f.startPrice = v
+	}
+	if v, ok := params["interval_ms"].(int); ok && v > 0 {
+		f.interval = time.Duration(v) * time.Millisecond
+	}
+
+	return f, nil
+}
+
+// Subscribe implements MarketDataFeed.
+func (f *paperFeed) Subscribe(ctx context.Context, symbols []string, ch chan<- MarketData) error {
+	prices := make(map[string]float64, len(symbols))
+	for _, symbol := range symbols {
+		prices[symbol] = f.startPrice
+	}
+
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			for _, symbol := range symbols {
+				prices[symbol] *= 1 + (rand.Float64()-0.5)*0.01
+				data := MarketData{
+					Symbol: symbol,
+					Price:  prices[symbol],
+					Volume: 1,
+					Time:   now,
+				}
+				select {
+				case ch <- data:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+	}
+}
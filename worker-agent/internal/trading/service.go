@@ -0,0 +1,293 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the top-level shape of a trading config YAML file: one or more
+// strategies, each wired to a named broker and market data feed.
+type Config struct {
+	Strategies []StrategyConfig `yaml:"strategies"`
+}
+
+// StrategyConfig configures a single running strategy instance.
+type StrategyConfig struct {
+	Name         string                 `yaml:"name"`   // registered strategy, e.g. "threshold"
+	Symbols      []string               `yaml:"symbols"`
+	Broker       string                 `yaml:"broker"` // registered broker, e.g. "paper"
+	Feed         string                 `yaml:"feed"`   // registered feed, e.g. "paper"
+	Params       map[string]interface{} `yaml:"params,omitempty"`
+	BrokerParams map[string]interface{} `yaml:"broker_params,omitempty"`
+	FeedParams   map[string]interface{} `yaml:"feed_params,omitempty"`
+}
+
+// priceUpdater is implemented by brokers (e.g. PaperBroker) that need to
+// observe feed ticks to fill market orders. It's an optional interface:
+// brokers backed by a real exchange connection don't need it.
+type priceUpdater interface {
+	UpdatePrice(symbol string, price float64)
+}
+
+// maxHistorySize bounds the recent orders/fills kept in memory for
+// diagnostics; older entries are dropped as new ones arrive.
+const maxHistorySize = 100
+
+// Service runs a set of configured strategies, each in its own goroutine,
+// and manages their lifecycle.
+type Service struct {
+	logger hclog.Logger
+
+	mu           sync.RWMutex
+	running      bool
+	startedAt    time.Time
+	configPath   string
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	orderHistory []Order
+	fillHistory  []Fill
+}
+
+// NewService creates a trading Service.
+func NewService(logger hclog.Logger) *Service {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+	return &Service{logger: logger}
+}
+
+// Start loads configPath and launches one goroutine per configured
+// strategy. It returns once every strategy has initialized successfully,
+// or the first error encountered (in which case no strategy is left
+// running).
+func (s *Service) Start(ctx context.Context, configPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("trading service already running")
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load trading config: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+
+	for _, sc := range cfg.Strategies {
+		if err := s.launch(runCtx, sc); err != nil {
+			cancel()
+			s.wg.Wait()
+			return err
+		}
+	}
+
+	s.cancel = cancel
+	s.running = true
+	s.startedAt = time.Now()
+	s.configPath = configPath
+	return nil
+}
+
+// launch instantiates and starts a single configured strategy.
+func (s *Service) launch(ctx context.Context, sc StrategyConfig) error {
+	factory, ok := LookupStrategy(sc.Name)
+	if !ok {
+		return fmt.Errorf("unknown strategy %q", sc.Name)
+	}
+	strat := factory()
+
+	broker, err := NewBroker(sc.Broker, sc.BrokerParams)
+	if err != nil {
+		return fmt.Errorf("failed to create broker for strategy %q: %w", sc.Name, err)
+	}
+
+	feed, err := NewFeed(sc.Feed, sc.FeedParams)
+	if err != nil {
+		return fmt.Errorf("failed to create market data feed for strategy %q: %w", sc.Name, err)
+	}
+
+	if err := strat.Init(ctx, sc.Params); err != nil {
+		return fmt.Errorf("failed to init strategy %q: %w", sc.Name, err)
+	}
+
+	s.wg.Add(1)
+	go s.run(ctx, sc.Name, strat, broker, feed, sc.Symbols)
+	return nil
+}
+
+// run is the per-strategy goroutine: it subscribes to the feed, dispatches
+// ticks to OnTick and resulting orders to the broker, dispatches fills to
+// OnFill, and calls Shutdown once ctx is canceled.
+func (s *Service) run(ctx context.Context, name string, strat Strategy, broker Broker, feed MarketDataFeed, symbols []string) {
+	defer s.wg.Done()
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := strat.Shutdown(shutdownCtx); err != nil {
+			s.logger.Error("strategy shutdown failed", "strategy", name, "error", err)
+		}
+	}()
+
+	ticks := make(chan MarketData, 16)
+	go func() {
+		if err := feed.Subscribe(ctx, symbols, ticks); err != nil && ctx.Err() == nil {
+			s.logger.Error("market data feed error", "strategy", name, "error", err)
+		}
+	}()
+
+	fills := broker.Fills()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-ticks:
+			if pu, ok := broker.(priceUpdater); ok {
+				pu.UpdatePrice(data.Symbol, data.Price)
+			}
+			orders, err := strat.OnTick(ctx, data)
+			if err != nil {
+				s.logger.Error("strategy OnTick failed", "strategy", name, "error", err)
+				continue
+			}
+			for _, order := range orders {
+				if _, err := broker.PlaceOrder(ctx, order); err != nil {
+					s.logger.Error("failed to place order", "strategy", name, "symbol", order.Symbol, "error", err)
+					continue
+				}
+				s.recordOrder(order)
+			}
+		case fill := <-fills:
+			s.recordFill(fill)
+			if err := strat.OnFill(ctx, fill); err != nil {
+				s.logger.Error("strategy OnFill failed", "strategy", name, "error", err)
+			}
+		}
+	}
+}
+
+// Stop signals every running strategy to shut down and waits for them to
+// finish, or for ctx to be canceled first.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("trading service not running")
+	}
+	cancel := s.cancel
+	s.running = false
+	s.mu.Unlock()
+
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetStatus returns the current trading status.
+func (s *Service) GetStatus() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.running {
+		return "running"
+	}
+	return "stopped"
+}
+
+// GetUptime returns how long trading has been running.
+func (s *Service) GetUptime() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.running {
+		return "0s"
+	}
+	return time.Since(s.startedAt).String()
+}
+
+// IsRunning returns whether trading is currently running.
+func (s *Service) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running
+}
+
+// ConfigPath returns the trading config path passed to the most recent
+// Start call, or "" if trading has never been started.
+func (s *Service) ConfigPath() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.configPath
+}
+
+// RecentOrders returns up to the last maxHistorySize orders placed, oldest
+// first.
+func (s *Service) RecentOrders() []Order {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Order, len(s.orderHistory))
+	copy(out, s.orderHistory)
+	return out
+}
+
+// RecentFills returns up to the last maxHistorySize fills received, oldest
+// first.
+func (s *Service) RecentFills() []Fill {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Fill, len(s.fillHistory))
+	copy(out, s.fillHistory)
+	return out
+}
+
+// recordOrder appends order to the bounded order history.
+func (s *Service) recordOrder(order Order) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orderHistory = append(s.orderHistory, order)
+	if len(s.orderHistory) > maxHistorySize {
+		s.orderHistory = s.orderHistory[len(s.orderHistory)-maxHistorySize:]
+	}
+}
+
+// recordFill appends fill to the bounded fill history.
+func (s *Service) recordFill(fill Fill) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fillHistory = append(s.fillHistory, fill)
+	if len(s.fillHistory) > maxHistorySize {
+		s.fillHistory = s.fillHistory[len(s.fillHistory)-maxHistorySize:]
+	}
+}
+
+// LoadConfig reads and parses a trading Config from a YAML file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,123 @@
+package trading
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeFeed forwards test-controlled ticks to Service.run's internal
+// channel one at a time, in order.
+type fakeFeed struct {
+	ticks chan MarketData
+}
+
+func (f *fakeFeed) Subscribe(ctx context.Context, symbols []string, ch chan<- MarketData) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case t := <-f.ticks:
+			ch <- t
+		}
+	}
+}
+
+// fakeBroker records every placed order and only reports a Fill when the
+// test sends one on fills, letting a test queue up ticks ahead of a fill
+// the way a sustained market price would.
+type fakeBroker struct {
+	fills chan Fill
+
+	mu     sync.Mutex
+	orders []Order
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{fills: make(chan Fill, 1)}
+}
+
+func (b *fakeBroker) PlaceOrder(ctx context.Context, order Order) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.orders = append(b.orders, order)
+	return "order-1", nil
+}
+
+func (b *fakeBroker) CancelOrder(ctx context.Context, orderID string) error {
+	return nil
+}
+
+func (b *fakeBroker) QueryOrder(ctx context.Context, orderID string) (OrderStatus, error) {
+	return OrderStatusFilled, nil
+}
+
+func (b *fakeBroker) Fills() <-chan Fill {
+	return b.fills
+}
+
+func (b *fakeBroker) orderCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.orders)
+}
+
+// countingStrategy wraps a Strategy and closes done once OnTick has been
+// called n times, so a test can wait for a batch of queued ticks to drain
+// through Service.run without sleeping.
+type countingStrategy struct {
+	Strategy
+	n     int32
+	seen  int32
+	done  chan struct{}
+	doneO sync.Once
+}
+
+func (c *countingStrategy) OnTick(ctx context.Context, data MarketData) ([]Order, error) {
+	orders, err := c.Strategy.OnTick(ctx, data)
+	if atomic.AddInt32(&c.seen, 1) == c.n {
+		c.doneO.Do(func() { close(c.done) })
+	}
+	return orders, err
+}
+
+// TestServiceRunDoesNotDuplicateOrderWhileAwaitingFill guards against the
+// race between Service.run's ticks and fills cases: several ticks queued
+// at a sustained buy_below price before their order's Fill arrives must
+// still only place one order, matching thresholdStrategy's "at most one
+// open position at a time" contract.
+func TestServiceRunDoesNotDuplicateOrderWhileAwaitingFill(t *testing.T) {
+	strat := newThresholdStrategy(t, map[string]interface{}{
+		"buy_below":  90.0,
+		"sell_above": 110.0,
+		"quantity":   1.0,
+	})
+	const numTicks = 5
+	cs := &countingStrategy{Strategy: strat, n: numTicks, done: make(chan struct{})}
+
+	broker := newFakeBroker()
+	feed := &fakeFeed{ticks: make(chan MarketData, numTicks)}
+
+	s := NewService(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.wg.Add(1)
+	go s.run(ctx, "test", cs, broker, feed, []string{"BTC-USD"})
+
+	for i := 0; i < numTicks; i++ {
+		feed.ticks <- MarketData{Symbol: "BTC-USD", Price: 85}
+	}
+
+	select {
+	case <-cs.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for queued ticks to be processed")
+	}
+
+	if got := broker.orderCount(); got != 1 {
+		t.Errorf("orders placed while awaiting fill = %d, want 1", got)
+	}
+}
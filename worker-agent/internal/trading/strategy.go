@@ -0,0 +1,44 @@
+package trading
+
+import (
+	"context"
+	"sync"
+)
+
+// Strategy implements a trading algorithm. Init runs once before the first
+// tick; OnTick is called for every MarketData update and returns the
+// orders (if any) the strategy wants to place; OnFill is called whenever
+// one of those orders executes; Shutdown runs once when the strategy is
+// stopped.
+type Strategy interface {
+	Init(ctx context.Context, cfg map[string]interface{}) error
+	OnTick(ctx context.Context, data MarketData) ([]Order, error)
+	OnFill(ctx context.Context, fill Fill) error
+	Shutdown(ctx context.Context) error
+}
+
+// StrategyFactory constructs a fresh Strategy instance.
+type StrategyFactory func() Strategy
+
+var (
+	strategyMu       sync.RWMutex
+	strategyRegistry = make(map[string]StrategyFactory)
+)
+
+// RegisterStrategy adds a named strategy to the registry, replacing any
+// previously registered factory for that name. Strategies register
+// themselves from an init() func, mirroring the plugin registration
+// pattern used by collector agents such as netdata go.d's module registry.
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategyMu.Lock()
+	defer strategyMu.Unlock()
+	strategyRegistry[name] = factory
+}
+
+// LookupStrategy returns the factory registered for name, if any.
+func LookupStrategy(name string) (StrategyFactory, bool) {
+	strategyMu.RLock()
+	defer strategyMu.RUnlock()
+	factory, ok := strategyRegistry[name]
+	return factory, ok
+}
@@ -0,0 +1,108 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+)
+
+func init() {
+	RegisterStrategy("threshold", func() Strategy { return &thresholdStrategy{} })
+}
+
+// thresholdStrategy is a reference Strategy implementation: it buys
+// quantity of a symbol once its price drops to or below buy_below, then
+// sells once it rises to or above sell_above, flipping back to looking for
+// a buy. It holds at most one open position at a time.
+type thresholdStrategy struct {
+	buyBelow  float64
+	sellAbove float64
+	quantity  float64
+	holding   bool
+
+	// pendingSide is the side of an order OnTick has placed but whose
+	// Fill hasn't arrived yet, or "" if none is in flight. Service.run
+	// dispatches ticks and fills for a strategy from the same goroutine
+	// but a buffered tick channel can hold several already-queued ticks
+	// at a sustained threshold price before the first order's fill is
+	// read; without this, each of those ticks would re-trigger OnTick's
+	// still-stale holding check and place a duplicate order.
+	pendingSide OrderSide
+}
+
+// Init implements Strategy.
+func (s *thresholdStrategy) Init(ctx context.Context, cfg map[string]interface{}) error {
+	buyBelow, ok := numberParam(cfg, "buy_below")
+	if !ok || buyBelow <= 0 {
+		return fmt.Errorf("threshold strategy requires a positive buy_below param")
+	}
+	sellAbove, ok := numberParam(cfg, "sell_above")
+	if !ok || sellAbove <= buyBelow {
+		return fmt.Errorf("threshold strategy requires sell_above > buy_below")
+	}
+	quantity, ok := numberParam(cfg, "quantity")
+	if !ok || quantity <= 0 {
+		return fmt.Errorf("threshold strategy requires a positive quantity param")
+	}
+
+	s.buyBelow = buyBelow
+	s.sellAbove = sellAbove
+	s.quantity = quantity
+	return nil
+}
+
+// numberParam reads key out of cfg as a float64, accepting both the
+// float64 and int Go types gopkg.in/yaml.v3 may decode a numeric scalar
+// into depending on whether it was written with a decimal point. ok is
+// false if key is absent or holds some other type.
+func numberParam(cfg map[string]interface{}, key string) (value float64, ok bool) {
+	switch n := cfg[key].(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// OnTick implements Strategy.
+func (s *thresholdStrategy) OnTick(ctx context.Context, data MarketData) ([]Order, error) {
+	if s.pendingSide != "" {
+		// Already have an order out awaiting its fill; repeated ticks at
+		// the same threshold must not pile up more orders on top of it.
+		return nil, nil
+	}
+
+	switch {
+	case !s.holding && data.Price <= s.buyBelow:
+		s.pendingSide = OrderSideBuy
+		return []Order{{
+			Symbol:   data.Symbol,
+			Side:     OrderSideBuy,
+			Type:     OrderTypeMarket,
+			Quantity: s.quantity,
+		}}, nil
+	case s.holding && data.Price >= s.sellAbove:
+		s.pendingSide = OrderSideSell
+		return []Order{{
+			Symbol:   data.Symbol,
+			Side:     OrderSideSell,
+			Type:     OrderTypeMarket,
+			Quantity: s.quantity,
+		}}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// OnFill implements Strategy.
+func (s *thresholdStrategy) OnFill(ctx context.Context, fill Fill) error {
+	s.holding = fill.Side == OrderSideBuy
+	s.pendingSide = ""
+	return nil
+}
+
+// Shutdown implements Strategy.
+func (s *thresholdStrategy) Shutdown(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,152 @@
+package trading
+
+import (
+	"context"
+	"testing"
+)
+
+func newThresholdStrategy(t *testing.T, cfg map[string]interface{}) Strategy {
+	t.Helper()
+	factory, ok := LookupStrategy("threshold")
+	if !ok {
+		t.Fatal("threshold strategy not registered")
+	}
+	s := factory()
+	if err := s.Init(context.Background(), cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	return s
+}
+
+func TestThresholdStrategyInitValidatesParams(t *testing.T) {
+	factory, ok := LookupStrategy("threshold")
+	if !ok {
+		t.Fatal("threshold strategy not registered")
+	}
+
+	cases := []map[string]interface{}{
+		{"sell_above": 110.0, "quantity": 1.0},
+		{"buy_below": 90.0, "quantity": 1.0},
+		{"buy_below": 90.0, "sell_above": 80.0, "quantity": 1.0},
+		{"buy_below": 90.0, "sell_above": 110.0},
+		{"buy_below": 90.0, "sell_above": 110.0, "quantity": 0.0},
+	}
+	for _, cfg := range cases {
+		if err := factory().Init(context.Background(), cfg); err == nil {
+			t.Errorf("Init(%v) = nil, want error", cfg)
+		}
+	}
+}
+
+func TestThresholdStrategyBuysBelowThenSellsAbove(t *testing.T) {
+	s := newThresholdStrategy(t, map[string]interface{}{
+		"buy_below":  90.0,
+		"sell_above": 110.0,
+		"quantity":   2.0,
+	})
+
+	orders, err := s.OnTick(context.Background(), MarketData{Symbol: "BTC-USD", Price: 100})
+	if err != nil {
+		t.Fatalf("OnTick: %v", err)
+	}
+	if len(orders) != 0 {
+		t.Fatalf("OnTick at 100 = %v, want no orders", orders)
+	}
+
+	orders, err = s.OnTick(context.Background(), MarketData{Symbol: "BTC-USD", Price: 85})
+	if err != nil {
+		t.Fatalf("OnTick: %v", err)
+	}
+	if len(orders) != 1 || orders[0].Side != OrderSideBuy || orders[0].Quantity != 2 {
+		t.Fatalf("OnTick at 85 = %+v, want a single buy order for 2", orders)
+	}
+
+	if err := s.OnFill(context.Background(), Fill{Side: OrderSideBuy}); err != nil {
+		t.Fatalf("OnFill: %v", err)
+	}
+
+	orders, err = s.OnTick(context.Background(), MarketData{Symbol: "BTC-USD", Price: 85})
+	if err != nil {
+		t.Fatalf("OnTick: %v", err)
+	}
+	if len(orders) != 0 {
+		t.Fatalf("OnTick at 85 while holding = %v, want no orders", orders)
+	}
+
+	orders, err = s.OnTick(context.Background(), MarketData{Symbol: "BTC-USD", Price: 115})
+	if err != nil {
+		t.Fatalf("OnTick: %v", err)
+	}
+	if len(orders) != 1 || orders[0].Side != OrderSideSell || orders[0].Quantity != 2 {
+		t.Fatalf("OnTick at 115 while holding = %+v, want a single sell order for 2", orders)
+	}
+
+	if err := s.OnFill(context.Background(), Fill{Side: OrderSideSell}); err != nil {
+		t.Fatalf("OnFill: %v", err)
+	}
+
+	orders, err = s.OnTick(context.Background(), MarketData{Symbol: "BTC-USD", Price: 85})
+	if err != nil {
+		t.Fatalf("OnTick: %v", err)
+	}
+	if len(orders) != 1 || orders[0].Side != OrderSideBuy {
+		t.Fatalf("OnTick at 85 after selling = %+v, want it to be willing to buy again", orders)
+	}
+}
+
+// TestThresholdStrategyInitAcceptsIntegerParams guards against
+// gopkg.in/yaml.v3 decoding a bare-integer scalar (e.g. "buy_below: 90")
+// into interface{} as a Go int rather than float64: Init must accept that
+// the same way it accepts "buy_below: 90.0".
+func TestThresholdStrategyInitAcceptsIntegerParams(t *testing.T) {
+	factory, ok := LookupStrategy("threshold")
+	if !ok {
+		t.Fatal("threshold strategy not registered")
+	}
+
+	cfg := map[string]interface{}{"buy_below": 90, "sell_above": 110, "quantity": 2}
+	if err := factory().Init(context.Background(), cfg); err != nil {
+		t.Errorf("Init(%v) = %v, want success for integer-valued params", cfg, err)
+	}
+}
+
+// TestThresholdStrategyDoesNotDuplicateOrderWhileAwaitingFill guards
+// against OnTick placing a second buy order for ticks that arrive (or are
+// processed) before the first order's Fill does; Service.run dispatches
+// both from the same goroutine, but a sustained below-threshold price can
+// queue several ticks ahead of a single fill.
+func TestThresholdStrategyDoesNotDuplicateOrderWhileAwaitingFill(t *testing.T) {
+	s := newThresholdStrategy(t, map[string]interface{}{
+		"buy_below":  90.0,
+		"sell_above": 110.0,
+		"quantity":   1.0,
+	})
+
+	for i := 0; i < 4; i++ {
+		orders, err := s.OnTick(context.Background(), MarketData{Symbol: "BTC-USD", Price: 85})
+		if err != nil {
+			t.Fatalf("OnTick #%d: %v", i, err)
+		}
+		if i == 0 {
+			if len(orders) != 1 {
+				t.Fatalf("OnTick #0 = %v, want a single buy order", orders)
+			}
+			continue
+		}
+		if len(orders) != 0 {
+			t.Errorf("OnTick #%d while awaiting fill = %v, want no orders", i, orders)
+		}
+	}
+
+	if err := s.OnFill(context.Background(), Fill{Side: OrderSideBuy}); err != nil {
+		t.Fatalf("OnFill: %v", err)
+	}
+
+	orders, err := s.OnTick(context.Background(), MarketData{Symbol: "BTC-USD", Price: 115})
+	if err != nil {
+		t.Fatalf("OnTick: %v", err)
+	}
+	if len(orders) != 1 || orders[0].Side != OrderSideSell {
+		t.Fatalf("OnTick at 115 after fill = %+v, want a sell order", orders)
+	}
+}
@@ -0,0 +1,61 @@
+// Package trading implements the worker agent's trading engine: pluggable
+// Strategy, Broker, and MarketDataFeed implementations wired together by a
+// Service that runs each configured strategy in its own goroutine.
+package trading
+
+import "time"
+
+// OrderSide is the direction of an Order.
+type OrderSide string
+
+const (
+	OrderSideBuy  OrderSide = "buy"
+	OrderSideSell OrderSide = "sell"
+)
+
+// OrderType is the pricing mode of an Order.
+type OrderType string
+
+const (
+	OrderTypeMarket OrderType = "market"
+	OrderTypeLimit  OrderType = "limit"
+)
+
+// OrderStatus is the broker-reported state of a previously placed Order.
+type OrderStatus string
+
+const (
+	OrderStatusOpen     OrderStatus = "open"
+	OrderStatusFilled   OrderStatus = "filled"
+	OrderStatusCanceled OrderStatus = "canceled"
+)
+
+// MarketData is a single price update for a symbol, delivered by a
+// MarketDataFeed to a running Strategy.
+type MarketData struct {
+	Symbol string
+	Price  float64
+	Volume float64
+	Time   time.Time
+}
+
+// Order is a strategy's request to buy or sell a symbol, submitted to a
+// Broker. Price is the limit price and is ignored for market orders.
+type Order struct {
+	ID       string
+	Symbol   string
+	Side     OrderSide
+	Type     OrderType
+	Quantity float64
+	Price    float64
+}
+
+// Fill reports that an Order (or part of it) executed.
+type Fill struct {
+	OrderID  string
+	Symbol   string
+	Side     OrderSide
+	Quantity float64
+	Price    float64
+	Time     time.Time
+}
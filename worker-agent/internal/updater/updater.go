@@ -0,0 +1,265 @@
+// Package updater implements the worker agent's self-update mechanism: it
+// fetches a release manifest from the SaaS, downloads the worker-agent
+// binary for the current platform, verifies a detached Ed25519 signature
+// against a pinned public key, and atomically swaps it into place.
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+)
+
+// prevSuffix and pendingSuffix name the sibling files Apply uses to back up
+// the previous binary and record an unconfirmed update, respectively.
+const (
+	prevSuffix    = ".prev"
+	pendingSuffix = ".pending"
+)
+
+// RollbackWindow is how long a freshly installed binary has to confirm
+// itself via ConfirmUpdate before WatchForConfirmation rolls it back.
+const RollbackWindow = 60 * time.Second
+
+// Config configures an Updater.
+type Config struct {
+	// BaseURL is the SaaS base URL the release manifest is fetched from.
+	BaseURL string
+	// CurrentVersion is the running agent's version, compared against the
+	// manifest to decide whether an update is available.
+	CurrentVersion string
+	// PublicKey verifies the detached signature over the downloaded
+	// binary. Apply refuses to install an update when this is empty,
+	// since an unverified update is effectively remote code execution.
+	PublicKey ed25519.PublicKey
+	// BinaryPath is the on-disk path of the running executable. Defaults
+	// to os.Executable() when empty.
+	BinaryPath string
+}
+
+// Updater checks for and applies worker-agent releases.
+type Updater struct {
+	cfg        Config
+	publicKey  atomic.Pointer[ed25519.PublicKey]
+	httpClient *http.Client
+	logger     hclog.Logger
+}
+
+// New creates an Updater from cfg.
+func New(cfg Config, logger hclog.Logger) (*Updater, error) {
+	if logger == nil {
+		logger = hclog.NewNullLogger()
+	}
+
+	if cfg.BinaryPath == "" {
+		path, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve running binary path: %w", err)
+		}
+		cfg.BinaryPath = path
+	}
+
+	u := &Updater{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 2 * time.Minute},
+		logger:     logger,
+	}
+	u.publicKey.Store(&cfg.PublicKey)
+	return u, nil
+}
+
+// SetPublicKey atomically swaps the Ed25519 public key Apply verifies
+// release signatures against, so a config hot reload of update_public_key
+// takes effect on the next update instead of only at the next restart. A
+// nil key disables self-update (Apply refuses to install unverified
+// binaries).
+func (u *Updater) SetPublicKey(key ed25519.PublicKey) {
+	u.publicKey.Store(&key)
+}
+
+// CheckForUpdate fetches the release manifest for the current platform and
+// reports whether it names a version different from CurrentVersion. The
+// SaaS is trusted to only ever publish a manifest naming a newer release.
+func (u *Updater) CheckForUpdate(ctx context.Context) (*api.ReleaseManifest, bool, error) {
+	url := fmt.Sprintf("%s/api/agent/releases/latest?os=%s&arch=%s", u.cfg.BaseURL, runtime.GOOS, runtime.GOARCH)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch release manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, false, fmt.Errorf("release manifest request failed: %s (status %d)", string(body), resp.StatusCode)
+	}
+
+	var manifest api.ReleaseManifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, false, fmt.Errorf("failed to decode release manifest: %w", err)
+	}
+
+	return &manifest, manifest.Version != u.cfg.CurrentVersion, nil
+}
+
+// Apply downloads, verifies, and installs the release described by
+// manifest. It backs up the current binary to BinaryPath+".prev" and
+// records an unconfirmed-update marker at BinaryPath+".pending" so a
+// caller can roll back if the new binary never confirms itself (see
+// WatchForConfirmation).
+func (u *Updater) Apply(ctx context.Context, manifest *api.ReleaseManifest) error {
+	publicKey := *u.publicKey.Load()
+	if len(publicKey) == 0 {
+		return fmt.Errorf("self-update public key not configured, refusing to install unverified binary")
+	}
+
+	data, err := u.download(ctx, manifest.URL)
+	if err != nil {
+		return fmt.Errorf("failed to download release: %w", err)
+	}
+
+	sig, err := decodeSignature(manifest.Signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, data, sig) {
+		return fmt.Errorf("release signature verification failed")
+	}
+
+	tmpPath := u.cfg.BinaryPath + ".new"
+	if err := os.WriteFile(tmpPath, data, 0755); err != nil {
+		return fmt.Errorf("failed to write new binary: %w", err)
+	}
+
+	if err := copyFile(u.cfg.BinaryPath, u.prevPath()); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to back up current binary: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, u.cfg.BinaryPath); err != nil {
+		return fmt.Errorf("failed to install new binary: %w", err)
+	}
+
+	if err := os.WriteFile(u.pendingPath(), []byte(u.cfg.CurrentVersion), 0644); err != nil {
+		u.logger.Warn("failed to record pending-update marker, rollback-on-failed-heartbeat is disabled for this update", "error", err)
+	}
+
+	u.logger.Info("installed new agent binary", "version", manifest.Version, "previous_version", u.cfg.CurrentVersion)
+	return nil
+}
+
+// Rollback restores BinaryPath from its ".prev" backup.
+func (u *Updater) Rollback() error {
+	if _, err := os.Stat(u.prevPath()); err != nil {
+		return fmt.Errorf("no previous binary to roll back to: %w", err)
+	}
+	return copyFile(u.prevPath(), u.cfg.BinaryPath)
+}
+
+// ConfirmUpdate clears the pending-update marker. It should be called once
+// the new binary has proven itself, e.g. after its first successful
+// heartbeat. Calling it when no update is pending is a no-op.
+func (u *Updater) ConfirmUpdate() error {
+	err := os.Remove(u.pendingPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// WatchForConfirmation blocks until ctx is canceled, ConfirmUpdate clears
+// the pending marker, or RollbackWindow elapses without confirmation. In
+// the latter case it restores the previous binary and returns a non-nil
+// error; the caller is expected to exit so the process supervisor
+// (systemd, launchd, NSSM, ...) restarts it into the restored binary. If no
+// update is pending when called, it returns immediately with a nil error.
+func (u *Updater) WatchForConfirmation(ctx context.Context) error {
+	if !u.updatePending() {
+		return nil
+	}
+
+	deadline := time.NewTimer(RollbackWindow)
+	defer deadline.Stop()
+
+	poll := time.NewTicker(time.Second)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline.C:
+			u.logger.Error("update not confirmed within rollback window, rolling back", "window", RollbackWindow)
+			if err := u.Rollback(); err != nil {
+				return fmt.Errorf("update unconfirmed and rollback failed: %w", err)
+			}
+			os.Remove(u.pendingPath())
+			return fmt.Errorf("update unconfirmed within %s, rolled back to previous binary", RollbackWindow)
+		case <-poll.C:
+			if !u.updatePending() {
+				return nil
+			}
+		}
+	}
+}
+
+func (u *Updater) updatePending() bool {
+	_, err := os.Stat(u.pendingPath())
+	return err == nil
+}
+
+func (u *Updater) prevPath() string {
+	return u.cfg.BinaryPath + prevSuffix
+}
+
+func (u *Updater) pendingPath() string {
+	return u.cfg.BinaryPath + pendingSuffix
+}
+
+func (u *Updater) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode())
+}
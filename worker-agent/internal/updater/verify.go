@@ -0,0 +1,20 @@
+package updater
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// decodeSignature base64-decodes a ReleaseManifest.Signature value.
+func decodeSignature(sig string) ([]byte, error) {
+	if sig == "" {
+		return nil, fmt.Errorf("release manifest has no signature")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,275 @@
+// Package ws implements the persistent WebSocket command channel between the
+// worker agent and the SaaS control plane.
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/finetunelab/worker-agent/pkg/api"
+	"nhooyr.io/websocket"
+	"nhooyr.io/websocket/wsjson"
+)
+
+const (
+	// pingInterval is how often we send a WS ping frame to keep the
+	// connection alive through intermediate proxies.
+	pingInterval = 20 * time.Second
+	// pingTimeout bounds how long we wait for the pong before treating the
+	// connection as dead.
+	pingTimeout = 10 * time.Second
+
+	minBackoff = 1 * time.Second
+	maxBackoff = 60 * time.Second
+)
+
+// CommandHandler executes a command received over the WS channel and
+// returns the result to send back.
+type CommandHandler func(ctx context.Context, cmd api.Command) api.CommandResult
+
+// StateHandler is notified whenever the WS channel transitions between
+// connected and disconnected. Agents use this to decide whether the HTTP
+// heartbeat loop needs to fall back to polling for commands.
+type StateHandler func(connected bool)
+
+// ResultFallback is invoked when a command_result frame cannot be written
+// back over the WS channel (e.g. the connection dropped mid-write). It
+// must durably queue the result through some other path, since the
+// caller has no further way to retry the WS delivery.
+type ResultFallback func(cmd api.Command, result api.CommandResult)
+
+// Client maintains a reconnecting WebSocket connection to the SaaS command
+// channel advertised by api.RegisterResponse.WebSocketURL.
+type Client struct {
+	url          string
+	apiKey       string
+	capabilities []string
+
+	onCommand      CommandHandler
+	onState        StateHandler
+	onResultFailed ResultFallback
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+// NewClient creates a new WS command channel client. onResultFailed is
+// called with any command_result that could not be written back over the
+// socket, so the caller can queue it through a durable path instead.
+func NewClient(url, apiKey string, capabilities []string, onCommand CommandHandler, onState StateHandler, onResultFailed ResultFallback) *Client {
+	return &Client{
+		url:            url,
+		apiKey:         apiKey,
+		capabilities:   capabilities,
+		onCommand:      onCommand,
+		onState:        onState,
+		onResultFailed: onResultFailed,
+	}
+}
+
+// Run dials the command channel and keeps it alive until ctx is canceled,
+// reconnecting with exponential backoff and jitter on any failure.
+func (c *Client) Run(ctx context.Context) {
+	backoff := minBackoff
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := c.runOnce(ctx); err != nil {
+			log.Printf("[WS] Connection error: %v", err)
+		}
+
+		c.setState(false)
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		wait := jitter(backoff)
+		log.Printf("[WS] Reconnecting in %v", wait)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// runOnce dials the channel, advertises capabilities, and pumps frames
+// until the connection drops or ctx is canceled.
+func (c *Client) runOnce(ctx context.Context) error {
+	header := http.Header{}
+	header.Set("X-API-Key", c.apiKey)
+
+	conn, _, err := websocket.Dial(ctx, c.url, &websocket.DialOptions{
+		HTTPHeader: header,
+	})
+	if err != nil {
+		return fmt.Errorf("dial failed: %w", err)
+	}
+	defer conn.CloseNow()
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+	}()
+
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := wsjson.Write(connCtx, conn, api.WSMessage{
+		Type: "capabilities",
+		Data: c.capabilities,
+	}); err != nil {
+		return fmt.Errorf("failed to send capabilities: %w", err)
+	}
+
+	log.Println("[WS] Command channel connected")
+	c.setState(true)
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 2)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errCh <- c.readLoop(connCtx, conn)
+	}()
+	go func() {
+		defer wg.Done()
+		errCh <- c.pingLoop(connCtx, conn)
+	}()
+
+	err = <-errCh
+	cancel()
+	wg.Wait()
+
+	return err
+}
+
+// readLoop reads inbound frames and dispatches command frames to the
+// handler, pushing command_result frames back onto the channel.
+func (c *Client) readLoop(ctx context.Context, conn *websocket.Conn) error {
+	for {
+		var msg api.WSMessage
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+
+		switch msg.Type {
+		case "command":
+			cmd, err := decodeCommand(msg.Data)
+			if err != nil {
+				log.Printf("[WS] Failed to decode command frame: %v", err)
+				continue
+			}
+			go c.handleCommand(ctx, conn, cmd)
+		case "heartbeat_ack":
+			// No-op: server acknowledging our liveness.
+		default:
+			log.Printf("[WS] Ignoring unknown frame type: %s", msg.Type)
+		}
+	}
+}
+
+// handleCommand runs the command through the agent's executor and writes
+// the result back as a command_result frame. If the write fails, the
+// result is handed to onResultFailed so it isn't silently dropped.
+func (c *Client) handleCommand(ctx context.Context, conn *websocket.Conn, cmd api.Command) {
+	result := c.onCommand(ctx, cmd)
+
+	if err := wsjson.Write(ctx, conn, api.WSMessage{
+		Type: "command_result",
+		Data: result,
+	}); err != nil {
+		log.Printf("[WS] Failed to send command_result for %s, falling back to durable delivery: %v", cmd.ID, err)
+		if c.onResultFailed != nil {
+			c.onResultFailed(cmd, result)
+		}
+	}
+}
+
+// pingLoop keeps the connection alive and detects dead peers.
+func (c *Client) pingLoop(ctx context.Context, conn *websocket.Conn) error {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return fmt.Errorf("ping failed: %w", err)
+			}
+		}
+	}
+}
+
+// Send pushes an arbitrary frame over the active connection, e.g. a
+// heartbeat keepalive. Returns an error if the channel is not connected.
+func (c *Client) Send(ctx context.Context, msg api.WSMessage) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil {
+		return fmt.Errorf("ws: not connected")
+	}
+
+	return wsjson.Write(ctx, conn, msg)
+}
+
+// Connected reports whether the command channel currently has a live
+// connection.
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+func (c *Client) setState(connected bool) {
+	if c.onState != nil {
+		c.onState(connected)
+	}
+}
+
+func decodeCommand(data interface{}) (api.Command, error) {
+	var cmd api.Command
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return cmd, err
+	}
+
+	if err := json.Unmarshal(raw, &cmd); err != nil {
+		return cmd, err
+	}
+
+	return cmd, nil
+}
+
+// jitter adds up to 50% random jitter on top of a base backoff duration.
+func jitter(base time.Duration) time.Duration {
+	return base + time.Duration(rand.Int63n(int64(base)/2+1))
+}
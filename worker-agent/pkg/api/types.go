@@ -15,6 +15,13 @@ const (
 	CommandRestartAgent CommandType = "restart_agent"
 	// CommandCollectDiag collects diagnostic information
 	CommandCollectDiag CommandType = "collect_diagnostics"
+	// CommandRunScript executes an operator-supplied script in a
+	// sandboxed environment
+	CommandRunScript CommandType = "run_script"
+	// CommandPurge forces an out-of-band sweep of the command spool
+	CommandPurge CommandType = "purge"
+	// CommandSetLogLevel changes the agent's log verbosity at runtime
+	CommandSetLogLevel CommandType = "set_log_level"
 )
 
 // Command represents a command sent from the SaaS to the worker
@@ -71,11 +78,45 @@ type HeartbeatResponse struct {
 
 // MetricsSnapshot represents system and application metrics at a point in time
 type MetricsSnapshot struct {
-	CPUPercent     float64 `json:"cpu_percent,omitempty"`
-	MemoryUsedMB   uint64  `json:"memory_used_mb,omitempty"`
-	MemoryTotalMB  uint64  `json:"memory_total_mb,omitempty"`
-	TradingStatus  string  `json:"trading_status,omitempty"`
-	ActiveTrades   int     `json:"active_trades,omitempty"`
+	CPUPercent    float64 `json:"cpu_percent,omitempty"`
+	MemoryUsedMB  uint64  `json:"memory_used_mb,omitempty"`
+	MemoryTotalMB uint64  `json:"memory_total_mb,omitempty"`
+	TradingStatus string  `json:"trading_status,omitempty"`
+	ActiveTrades  int     `json:"active_trades,omitempty"`
+
+	DiskUsedGB    float64 `json:"disk_used_gb,omitempty"`
+	DiskTotalGB   float64 `json:"disk_total_gb,omitempty"`
+	DiskReadMBps  float64 `json:"disk_read_mbps,omitempty"`
+	DiskWriteMBps float64 `json:"disk_write_mbps,omitempty"`
+
+	NetSentMBps float64 `json:"net_sent_mbps,omitempty"`
+	NetRecvMBps float64 `json:"net_recv_mbps,omitempty"`
+
+	LoadAvg1  float64 `json:"load_avg_1,omitempty"`
+	LoadAvg5  float64 `json:"load_avg_5,omitempty"`
+	LoadAvg15 float64 `json:"load_avg_15,omitempty"`
+
+	PerProcess []ProcessMetric `json:"per_process,omitempty"`
+	GPUs       []GPUMetric     `json:"gpus,omitempty"`
+}
+
+// ProcessMetric represents resource usage for a single allowlisted process.
+type ProcessMetric struct {
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemoryRSSMB uint64 `json:"memory_rss_mb"`
+}
+
+// GPUMetric represents telemetry for a single GPU, collected via NVML on
+// workers that expose one.
+type GPUMetric struct {
+	Index              int     `json:"index"`
+	Name               string  `json:"name"`
+	UtilizationPercent float64 `json:"utilization_percent"`
+	MemoryUsedMB       uint64  `json:"memory_used_mb"`
+	MemoryTotalMB      uint64  `json:"memory_total_mb"`
+	TemperatureC       float64 `json:"temperature_c"`
 }
 
 // MetricsBatch represents a batch of metrics to send
@@ -84,6 +125,17 @@ type MetricsBatch struct {
 	Metrics  []MetricsSnapshot `json:"metrics"`
 }
 
+// ReleaseManifest describes the latest worker-agent release available for
+// a given platform/architecture, served by the SaaS at
+// /api/agent/releases/latest.
+type ReleaseManifest struct {
+	Version   string `json:"version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	URL       string `json:"url"`
+	Signature string `json:"signature"` // base64-encoded Ed25519 signature over the downloaded binary
+}
+
 // WSMessage represents a WebSocket message
 type WSMessage struct {
 	Type string      `json:"type"` // command, heartbeat, heartbeat_ack, command_result